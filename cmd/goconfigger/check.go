@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines surrounding each change, in a line diff.
+const diffContext = 3
+
+type lineOp struct {
+	// Kind is one of ' ' (unchanged), '-' (only in the old lines), or '+' (only in the new lines).
+	Kind byte
+	Line string
+}
+
+// checkOutput compares output, which would be written to the file at name, to its current contents, returning a
+// description of the difference, or an empty string if they are the same. The comparison is byte-wise, unless semantic,
+// in which case both are parsed as format, and the result is a list of the changed paths, see formatChanges.
+func checkOutput(p parser.Config, format parser.Format, name string, output []byte, semantic bool) (string, error) {
+	existing, err := ioutil.ReadFile(name)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		return fmt.Sprintf("%s doesn't exist\n", name), nil
+	}
+
+	if !semantic {
+		if bytes.Equal(existing, output) {
+			return "", nil
+		}
+		return fmt.Sprintf("--- %s\n+++ %s (expected)\n", name, name) + formatLineDiff(diffLines(string(existing), string(output))), nil
+	}
+
+	a, err := p.Read(format, bytes.NewReader(existing))
+	if err != nil {
+		return fmt.Sprintf("unable to parse %s: %s\n", name, err.Error()), nil
+	}
+	b, err := p.Read(format, bytes.NewReader(output))
+	if err != nil {
+		return "", err
+	}
+	if reflect.DeepEqual(a, b) {
+		return "", nil
+	}
+	return formatChanges(merge.Diff(a, b)), nil
+}
+
+// diffLines returns the operations transforming the lines of a into the lines of b, using the Myers algorithm, in
+// linear space, see diffRange.
+func diffLines(a, b string) []lineOp {
+	x, y := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+	// SplitAfter returns a trailing empty string, if the input ends with a newline
+	if x[len(x)-1] == "" {
+		x = x[:len(x)-1]
+	}
+	if y[len(y)-1] == "" {
+		y = y[:len(y)-1]
+	}
+	var ops []lineOp
+	diffRange(x, y, &ops)
+	return ops
+}
+
+// diffRange appends the operations transforming x into y to ops, trimming any common prefix and suffix, then
+// recursively diffing either side of the middle of a shortest edit script, see bisect.
+func diffRange(x, y []string, ops *[]lineOp) {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		*ops = append(*ops, lineOp{' ', x[prefix]})
+		prefix++
+	}
+	x, y = x[prefix:], y[prefix:]
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-suffix-1] == y[len(y)-suffix-1] {
+		suffix++
+	}
+	common := x[len(x)-suffix:]
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+
+	// the split must make progress, which is only a safeguard
+	switch i, j, ok := bisect(x, y); {
+	case ok && i+j > 0 && i+j < len(x)+len(y):
+		diffRange(x[:i], y[:j], ops)
+		diffRange(x[i:], y[j:], ops)
+	default:
+		for _, line := range x {
+			*ops = append(*ops, lineOp{'-', line})
+		}
+		for _, line := range y {
+			*ops = append(*ops, lineOp{'+', line})
+		}
+	}
+
+	for _, line := range common {
+		*ops = append(*ops, lineOp{' ', line})
+	}
+}
+
+// bisect finds the point at which the forward and reverse paths of a shortest edit script of x into y overlap, per
+// "An O(ND) Difference Algorithm and Its Variations" (Myers, 1986), which must have no common prefix or suffix,
+// returning false if x or y is empty, or they have nothing in common.
+func bisect(x, y []string) (int, int, bool) {
+	n, m := len(x), len(y)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	// every diagonal k, for -maxD <= k <= maxD
+	offset, size := maxD, 2*maxD+2
+	// forward[offset+k] and reverse[offset+k] are the furthest x reached on diagonal k, from each end, or -1
+	forward, reverse := make([]int, size), make([]int, size)
+	for i := range forward {
+		forward[i], reverse[i] = -1, -1
+	}
+	forward[offset+1], reverse[offset+1] = 0, 0
+	delta := n - m
+	// if delta is odd, the paths overlap when extending the forward path, otherwise the reverse path
+	front := delta%2 != 0
+	// the bounds of the diagonals to search, which shrink as paths go beyond the edges
+	var kStart, kEnd, rStart, rEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			var i int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				i = forward[offset+k+1]
+			} else {
+				i = forward[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			forward[offset+k] = i
+			switch {
+			case i > n:
+				kEnd += 2
+			case j > m:
+				kStart += 2
+			case front:
+				if r := offset + delta - k; r >= 0 && r < size && reverse[r] != -1 && i >= n-reverse[r] {
+					return i, j, true
+				}
+			}
+		}
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var i int
+			if k == -d || (k != d && reverse[offset+k-1] < reverse[offset+k+1]) {
+				i = reverse[offset+k+1]
+			} else {
+				i = reverse[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[n-i-1] == y[m-j-1] {
+				i++
+				j++
+			}
+			reverse[offset+k] = i
+			switch {
+			case i > n:
+				rEnd += 2
+			case j > m:
+				rStart += 2
+			case !front:
+				if f := offset + delta - k; f >= 0 && f < size && forward[f] != -1 && forward[f] >= n-i {
+					return forward[f], forward[f] - (f - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// formatLineDiff formats ops in the unified diff format, as hunks of changes with surrounding context.
+func formatLineDiff(ops []lineOp) string {
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].Kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk until there are more than twice the context of unchanged lines
+		end, unchanged := start, 0
+		for i := start; i < len(ops) && unchanged <= 2*diffContext; i++ {
+			if ops[i].Kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+				end = i + 1
+			}
+		}
+		from, to := start-diffContext, end+diffContext
+		if from < 0 {
+			from = 0
+		}
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		// the line numbers of the hunk, in each side
+		var oldLine, newLine, oldCount, newCount int
+		for _, op := range ops[:from] {
+			if op.Kind != '+' {
+				oldLine++
+			}
+			if op.Kind != '-' {
+				newLine++
+			}
+		}
+		for _, op := range ops[from:to] {
+			if op.Kind != '+' {
+				oldCount++
+			}
+			if op.Kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range ops[from:to] {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a hunk header, where start is the number of preceding lines.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
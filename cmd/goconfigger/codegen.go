@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joeycumines/go-configger/codegen"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+)
+
+const codegenGoUsageText = `goconfigger codegen go [OPTIONS] [--] CONFIG [CONFIG...]
+    prints Go types describing every CONFIG, each of which is an example,
+    rather than being merged, where fields that don't exist in every example
+    (or may be null) are pointers
+    CONFIG: see goconfigger help, where each option applies to every CONFIG`
+
+func codegenCommand() cli.Command {
+	return cli.Command{
+		Name:  "codegen",
+		Usage: "generate source code from CONFIGs",
+		Subcommands: []cli.Command{
+			{
+				Name:           "go",
+				Usage:          "print Go struct definitions, with json and yaml tags, inferred from example CONFIGs",
+				UsageText:      codegenGoUsageText,
+				SkipArgReorder: true,
+				Flags: append(
+					[]cli.Flag{
+						cli.StringFlag{
+							Name:  "package",
+							Value: "config",
+							Usage: "the name of the package",
+						},
+						cli.StringFlag{
+							Name:  "type",
+							Value: "Config",
+							Usage: "the name of the root type, which prefixes the names of any nested types",
+						},
+					},
+					mergeFlags()...,
+				),
+				Action: codegenGoAction,
+			},
+		},
+	}
+}
+
+func codegenGoAction(c *cli.Context) error {
+	p, err := newPipeline(c)
+	if err != nil {
+		return err
+	}
+	inputList, err := p.Inputs(c.Args())
+	if err != nil {
+		return err
+	}
+
+	examples, err := p.Examples(inputList)
+	if err != nil {
+		return err
+	}
+
+	b, err := codegen.Go(codegen.GoOptions{Package: c.String("package"), Type: c.String("type")}, examples...)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("unable to generate go: %s", err.Error()), CodeBadArgument)
+	}
+	fmt.Fprint(os.Stdout, string(b))
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strings"
+)
+
+const diffUsageText = `goconfigger diff [OPTIONS] [--] CONFIG CONFIG
+   goconfigger diff [OPTIONS] [--] CONFIG [CONFIG...] --to CONFIG [CONFIG...]
+    compares two configs, or the merged results of two stacks of configs,
+    separated by --to, exiting with code 18 if they differ
+    CONFIG: see goconfigger help, where each option applies to both sides`
+
+// diffFormats are the supported values of the diff --format option.
+var diffFormats = []string{"text", "json", "json-patch", "merge-patch"}
+
+func diffCommand() cli.Command {
+	return cli.Command{
+		Name:           "diff",
+		Usage:          "print the paths added, removed, or changed between two (merged) CONFIGs",
+		UsageText:      diffUsageText,
+		SkipArgReorder: true,
+		Flags: append(
+			[]cli.Flag{
+				cli.StringFlag{
+					Name:  "format,f",
+					Value: "text",
+					Usage: "output format, one of (" + strings.Join(diffFormats, ", ") + "), where json-patch and merge-patch transform the first into the second",
+				},
+			},
+			mergeFlags()...,
+		),
+		Action: diffAction,
+	}
+}
+
+func diffAction(c *cli.Context) error {
+	format := strings.ToLower(c.String("format"))
+	if !stringInSlice(format, diffFormats) {
+		return cli.NewExitError("invalid --format: "+c.String("format"), CodeBadArgument)
+	}
+
+	p, err := newPipeline(c)
+	if err != nil {
+		return err
+	}
+
+	// split the arguments into each side, either at --to, or each of exactly two CONFIGs
+	var sides [2][]mergeTarget
+	args := []string(c.Args())
+	split := -1
+	for i, arg := range args {
+		if arg == "--to" {
+			split = i
+			break
+		}
+	}
+	if split >= 0 {
+		if sides[0], err = p.Inputs(args[:split]); err != nil {
+			return err
+		}
+		if sides[1], err = p.Inputs(args[split+1:]); err != nil {
+			return err
+		}
+	} else {
+		inputList, err := p.Inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(inputList) != 2 {
+			return cli.NewExitError(fmt.Sprintf("expected 2 configs but got %d, use --to to separate stacks of configs", len(inputList)), CodeBadArgument)
+		}
+		sides[0], sides[1] = inputList[:1], inputList[1:]
+	}
+
+	var values [2]interface{}
+	for i, inputList := range sides {
+		if values[i], _, err = p.Merge(inputList); err != nil {
+			return err
+		}
+	}
+
+	changes := merge.Diff(values[0], values[1])
+
+	var output interface{}
+	switch format {
+	case "text":
+		fmt.Print(formatChanges(changes))
+	case "json":
+		list := make([]interface{}, 0, len(changes))
+		for _, change := range changes {
+			v := map[string]interface{}{
+				"path": change.Path.String(),
+				"type": change.Type.String(),
+			}
+			if change.Type != merge.Added {
+				v["old"] = change.Old
+			}
+			if change.Type != merge.Removed {
+				v["new"] = change.New
+			}
+			list = append(list, v)
+		}
+		output = list
+	case "json-patch":
+		output = merge.CreateJSONPatch(values[0], values[1])
+	case "merge-patch":
+		output = merge.CreateMergePatch(values[0], values[1])
+	}
+	if output != nil {
+		buffer := bytes.NewBufferString("")
+		if err := p.Parser.Write(parser.JSON, output, buffer); err != nil {
+			return cli.NewExitError(fmt.Sprintf("unable to output to format %v: %s", parser.JSON, err.Error()), CodeWriteError)
+		}
+		fmt.Fprint(os.Stdout, buffer.String())
+	}
+
+	if len(changes) != 0 {
+		return cli.NewExitError("", CodeDiffers)
+	}
+	return nil
+}
+
+// formatChanges formats each change on a line, prefixed by +, -, or ~, for added, removed, or changed, where values
+// are encoded as json, and the root is ".".
+func formatChanges(changes []merge.Change) string {
+	var b strings.Builder
+	for _, change := range changes {
+		path := change.Path.String()
+		if path == "" {
+			path = "."
+		}
+		switch change.Type {
+		case merge.Added:
+			fmt.Fprintf(&b, "+ %s: %s\n", path, formatJSON(change.New))
+		case merge.Removed:
+			fmt.Fprintf(&b, "- %s: %s\n", path, formatJSON(change.Old))
+		case merge.Changed:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", path, formatJSON(change.Old), formatJSON(change.New))
+		}
+	}
+	return b.String()
+}
+
+// formatJSON encodes v as compact json, falling back to the default format for unsupported values.
+func formatJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// writeExplain writes a table of the provenance of each leaf, see merge.Mode.Explain.
+func writeExplain(w io.Writer, list []merge.Provenance) error {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tVALUE\tSOURCE\tOVERRIDES")
+	for _, p := range list {
+		value, err := json.Marshal(p.Value)
+		if err != nil {
+			return err
+		}
+		overridden := make([]string, 0, len(p.Overridden))
+		for _, origin := range p.Overridden {
+			overridden = append(overridden, origin.String())
+		}
+		path := p.Path.String()
+		if path == "" {
+			path = "."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", path, value, p.Origin, strings.Join(overridden, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// the last column may be empty
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		if _, err := io.WriteString(w, strings.TrimRight(line, " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceLines finds the line numbers of each path within a config, if it is supported for the format, see
+// merge.Mode.SourceLines.
+func sourceLines(format parser.Format, b []byte) map[string]int {
+	switch format {
+	case parser.JSON:
+		return jsonLines(b)
+	case parser.YAML:
+		return yamlLines(b)
+	case parser.Env, parser.EnvSimple:
+		return envLines(b)
+	}
+	return nil
+}
+
+// jsonLines finds the line of each value in a json document, or as much as could be read, if it is invalid.
+func jsonLines(b []byte) map[string]int {
+	var (
+		result  = make(map[string]int)
+		decoder = json.NewDecoder(bytes.NewReader(b))
+		line    = func() int { return 1 + bytes.Count(b[:decoder.InputOffset()], []byte("\n")) }
+		value   func(path merge.Path) bool
+	)
+	decoder.UseNumber()
+	value = func(path merge.Path) bool {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		result[path.String()] = line()
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return false
+				}
+				k, _ := key.(string)
+				if !value(append(path, merge.Segment{Value: k})) {
+					return false
+				}
+			}
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if !value(append(path, merge.Segment{Value: strconv.Itoa(i), Index: true})) {
+					return false
+				}
+			}
+		default:
+			return true
+		}
+		_, err = decoder.Token()
+		return err == nil
+	}
+	value(make(merge.Path, 0))
+	return result
+}
+
+// yamlLines finds the line of each value in the first document of a yaml stream, where the line of a value within a
+// mapping is that of its key, and values within an alias, or merge key (<<), are at the lines of the anchored node.
+func yamlLines(b []byte) map[string]int {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(&doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	type entry struct {
+		node *yaml.Node
+		line int
+	}
+	var (
+		result  = make(map[string]int)
+		value   func(node *yaml.Node, path merge.Path, line int)
+		entries func(node *yaml.Node, m map[string]entry)
+	)
+	value = func(node *yaml.Node, path merge.Path, line int) {
+		result[path.String()] = line
+		switch node = resolveAlias(node); node.Kind {
+		case yaml.MappingNode:
+			m := make(map[string]entry)
+			entries(node, m)
+			for k, e := range m {
+				value(e.node, append(path, merge.Segment{Value: k}), e.line)
+			}
+		case yaml.SequenceNode:
+			for i, v := range node.Content {
+				value(v, append(path, merge.Segment{Value: strconv.Itoa(i), Index: true}), v.Line)
+			}
+		}
+	}
+	// entries adds the keys of a mapping to m, where merged keys are overridden by any explicit keys, regardless of
+	// the order
+	entries = func(node *yaml.Node, m map[string]entry) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag != "!!merge" {
+				continue
+			}
+			merged := []*yaml.Node{resolveAlias(node.Content[i+1])}
+			if merged[0].Kind == yaml.SequenceNode {
+				merged = merged[0].Content
+			}
+			// earlier mappings take precedence
+			for j := len(merged) - 1; j >= 0; j-- {
+				if n := resolveAlias(merged[j]); n.Kind == yaml.MappingNode {
+					entries(n, m)
+				}
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Tag != "!!merge" {
+				m[key.Value] = entry{node.Content[i+1], key.Line}
+			}
+		}
+	}
+	root := doc.Content[0]
+	value(root, make(merge.Path, 0), root.Line)
+	return result
+}
+
+// resolveAlias returns the node anchored by an alias, or node, if it isn't one.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for depth := 0; node.Kind == yaml.AliasNode && node.Alias != nil && depth < 100; depth++ {
+		node = node.Alias
+	}
+	return node
+}
+
+// envLines finds the line of each variable in a .env file.
+func envLines(b []byte) map[string]int {
+	result := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		s = strings.TrimPrefix(s, "export ")
+		if i := strings.IndexAny(s, "=:"); i > 0 {
+			// the last definition wins
+			result[merge.Path{{Value: strings.TrimSpace(s[:i])}}.String()] = line
+		}
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fileFinder expands directory and glob CONFIG arguments into the files they contain, in lexical order.
+type fileFinder struct {
+	Recursive bool
+	// Include and Exclude filter the files found, matching against either the base name, or, for patterns
+	// containing a slash, the slash separated path relative to the directory or glob root.
+	Include []string
+	Exclude []string
+}
+
+// Expand returns the files a directory or glob pattern resolves to, or nil if p is neither, and should be treated as
+// a single file.
+func (f fileFinder) Expand(p string) ([]string, error) {
+	if stats, err := os.Stat(p); err == nil {
+		if !stats.IsDir() {
+			return nil, nil
+		}
+		return f.dir(p, "")
+	}
+
+	if !hasGlobMeta(p) {
+		return nil, nil
+	}
+
+	files, err := f.glob(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match '%s'", p)
+	}
+	return files, nil
+}
+
+// dir returns the files in the directory root, where prefix is the slash separated path of root relative to any glob
+// root, which is prepended to the relative path of each file, for the Include and Exclude filters.
+func (f fileFinder) dir(root string, prefix string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && !f.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err != nil {
+			return err
+		} else if ok, err := f.match(path.Join(prefix, filepath.ToSlash(rel))); err != nil {
+			return err
+		} else if ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// glob implements shell-independent matching of pattern, where each slash separated segment is matched using
+// path.Match, and a segment of "**" matches any number of directories. Any matched directories are expanded
+// (non-recursively, unless the Recursive option is set) in place.
+func (f fileFinder) glob(pattern string) ([]string, error) {
+	segments := strings.Split(strings.TrimRight(filepath.ToSlash(pattern), "/"), "/")
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %s", pattern, err.Error())
+		}
+	}
+
+	// walk from the longest prefix that doesn't require matching
+	var root string
+	for len(segments) != 0 && !hasGlobMeta(segments[0]) {
+		root = path.Join(root, segments[0])
+		if root == "" {
+			root = "/"
+		}
+		segments = segments[1:]
+	}
+	if root == "" {
+		root = "."
+	}
+	root = filepath.FromSlash(root)
+
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		relSegments := strings.Split(filepath.ToSlash(rel), "/")
+		if !globMatch(segments, relSegments) {
+			if d.IsDir() && !globCanMatch(segments, relSegments) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if ok, err := f.match(filepath.ToSlash(rel)); err != nil {
+				return err
+			} else if ok {
+				files = append(files, p)
+			}
+			return nil
+		}
+		found, err := f.dir(p, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// match applies the Include and Exclude filters to a slash separated relative path.
+func (f fileFinder) match(rel string) (bool, error) {
+	matches := func(patterns []string) (bool, error) {
+		for _, pattern := range patterns {
+			name := path.Base(rel)
+			if strings.Contains(pattern, "/") {
+				name = rel
+			}
+			if ok, err := path.Match(pattern, name); err != nil {
+				return false, fmt.Errorf("invalid pattern '%s': %s", pattern, err.Error())
+			} else if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if len(f.Include) != 0 {
+		if ok, err := matches(f.Include); err != nil || !ok {
+			return false, err
+		}
+	}
+	if ok, err := matches(f.Exclude); err != nil || ok {
+		return false, err
+	}
+	return true, nil
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[`)
+}
+
+func globMatch(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if globMatch(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return globMatch(pattern[1:], segments[1:])
+}
+
+// globCanMatch returns true if a directory at segments may contain paths matching pattern.
+func globCanMatch(pattern, segments []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return globCanMatch(pattern[1:], segments[1:])
+}
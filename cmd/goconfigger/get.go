@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const getUsageText = `goconfigger get [OPTIONS] [--] PATH CONFIG [CONFIG...]
+    PATH: the path of the value to print, e.g. a.b[0]["c.d"], where scalars
+      are printed as-is (strings are unquoted), and anything else is printed
+      in the target format
+    CONFIG: see goconfigger help`
+
+func getCommand() cli.Command {
+	return cli.Command{
+		Name:           "get",
+		Usage:          "print the value at PATH, within the merged CONFIGs",
+		UsageText:      getUsageText,
+		SkipArgReorder: true,
+		Flags: append(
+			[]cli.Flag{
+				formatFlag(),
+				cli.StringFlag{
+					Name:  "default",
+					Usage: "print this instead, if PATH doesn't exist, rather than failing",
+				},
+			},
+			mergeFlags()...,
+		),
+		Action: getAction,
+	}
+}
+
+func getAction(c *cli.Context) error {
+	args := c.Args()
+	if len(args) == 0 {
+		return cli.NewExitError("missing PATH", CodeBadArgument)
+	}
+	path, err := merge.ParsePath(args[0])
+	if err != nil {
+		return cli.NewExitError(err.Error(), CodeBadArgument)
+	}
+	if !path.Literal() {
+		return cli.NewExitError(fmt.Sprintf("invalid path '%s': wildcards aren't supported", args[0]), CodeBadArgument)
+	}
+
+	var targetFormat parser.Format
+	if flag := c.String("format"); flag != "" {
+		format, ok := AppFormats()[strings.ToLower(flag)]
+		if !ok {
+			return cli.NewExitError("unable to determine the format from: "+flag, CodeBadFormat)
+		}
+		targetFormat = format
+	}
+
+	p, err := newPipeline(c)
+	if err != nil {
+		return err
+	}
+	inputList, err := p.Inputs(args[1:])
+	if err != nil {
+		return err
+	}
+	if targetFormat == parser.Auto {
+		targetFormat = defaultFormat(inputList)
+	}
+	data, _, err := p.Merge(inputList)
+	if err != nil {
+		return err
+	}
+
+	value, ok, _ := merge.Get(data, path)
+	if !ok {
+		if c.IsSet("default") {
+			fmt.Println(c.String("default"))
+			return nil
+		}
+		return cli.NewExitError(fmt.Sprintf("path not found: %s", args[0]), CodeNotFound)
+	}
+
+	if s, ok := formatScalar(value); ok {
+		fmt.Println(s)
+		return nil
+	}
+
+	buffer := bytes.NewBufferString("")
+	if err := p.Parser.Write(targetFormat, value, buffer); err != nil {
+		return cli.NewExitError(fmt.Sprintf("unable to output to format %v: %s", targetFormat, err.Error()), CodeWriteError)
+	}
+	fmt.Fprint(os.Stdout, buffer.String())
+
+	return nil
+}
+
+// formatScalar formats v for printing, if it is a scalar (or null), where strings are unquoted.
+func formatScalar(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return t, true
+	case bool:
+		return strconv.FormatBool(t), true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	}
+	return "", false
+}
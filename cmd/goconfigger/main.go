@@ -4,21 +4,30 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/joeycumines/go-configger/parser"
+	"github.com/joeycumines/go-configger/query"
+	"github.com/joeycumines/go-configger/schema"
 	"gopkg.in/urfave/cli.v1"
 	"gopkg.in/yaml.v2"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
-	"strconv"
+	"path/filepath"
 	"strings"
 )
 
 const (
-	CodeBadFormat   = 10
-	CodeReadError   = 11
-	CodeNoTargets   = 12
-	CodeWriteError  = 13
-	CodeBadArgument = 14
+	CodeBadFormat       = 10
+	CodeReadError       = 11
+	CodeNoTargets       = 12
+	CodeWriteError      = 13
+	CodeBadArgument     = 14
+	CodePatchError      = 15
+	CodeConflict        = 16
+	CodeNotFound        = 17
+	CodeDiffers         = 18
+	CodeCheckFailed     = 19
+	CodeSchemaViolation = 20
 )
 
 var (
@@ -26,19 +35,29 @@ var (
 	AppVersion   = `1.0.0`
 	AppUsage     = `output a modified configuration file, allowing merging, modification, and conversion`
 	AppUsageText = `goconfigger [OPTIONS] [--] CONFIG [CONFIG...]
-    CONFIG: [--FORMAT [...FORMAT_ARGS]] PATH
-      FORMAT: json|yaml|yml|yaml-index|yml-index|env|env-simple
+    --: the first separator ends OPTIONS (needed if the first CONFIG starts
+      with a FORMAT), any subsequent separator ends FORMAT parsing, treating
+      every argument that follows as a literal PATH (e.g. a file named --json)
+    CONFIG: [--FORMAT [...FORMAT_ARGS]] [--] PATH
+      FORMAT: json|yaml|yml|yaml-index|yml-index|env|env-simple|
+          patch|json-patch|yaml-patch|yml-patch
         if provided, FORMAT will override the file extension of PATH
+        the patch formats mark PATH as a JSON Patch (RFC 6902), which is
+        applied to the result so far, rather than merged, where patch
+        uses the file extension to determine the format
       FORMAT_ARGS:
         yaml-index|yml-index: INDEX
           allows selection of a single document from a (potentially)
           multi-document yaml file
-      PATH: a valid path to a valid config file`
-	AppAction  = appAction
-	AppArgs    = os.Args
-	AppFlags   = appFlags
-	AppFormats = appFormats
-	AppParser  = appParser
+      PATH: a valid path to a valid config file, or - to read stdin, or a
+        directory or glob pattern (supporting **), which will be expanded
+        to the files it contains, in lexical order`
+	AppAction   = appAction
+	AppArgs     = os.Args
+	AppCommands = appCommands
+	AppFlags    = appFlags
+	AppFormats  = appFormats
+	AppParser   = appParser
 )
 
 func main() {
@@ -47,6 +66,7 @@ func main() {
 	app.Usage = AppUsage
 	app.Flags = AppFlags()
 	app.Action = AppAction
+	app.Commands = AppCommands()
 	app.UsageText = AppUsageText
 	app.Version = AppVersion
 	app.Run(AppArgs)
@@ -55,289 +75,427 @@ func main() {
 type mergeTarget struct {
 	Format parser.Format
 	Reader io.Reader
+	Path   string
+	Stdin  bool
+	// Index is the selected yaml document, or -1 if the whole stream was used
+	Index int
+	// Patch indicates that the target is a JSON Patch, to apply to the result so far
+	Patch bool
+	// Expanded indicates that the target was found by expanding a directory or glob pattern
+	Expanded bool
 }
 
-type Node struct {
-	Path      string
-	Whitelist bool
-	Blacklist bool
+// Name identifies the target, for reporting.
+func (t mergeTarget) Name() string {
+	name := t.Path
+	if t.Stdin {
+		name = "stdin"
+	}
+	if t.Index >= 0 {
+		name = fmt.Sprintf("%s (document %d)", name, t.Index)
+	}
+	return name
 }
 
-type Mode map[string]*Node
+func appAction(c *cli.Context) error {
+	appFormats := AppFormats()
+	appParser := AppParser()
 
-func (m Mode) Included(s string) bool {
-	if m == nil {
-		return true
+	var (
+		targetFormat parser.Format
+		inPlace      = c.Bool("in-place")
+		explain      = c.Bool("explain")
+		backup       = c.String("backup")
+		output       = c.String("output")
+		check        = c.Bool("check")
+	)
+
+	if backup != "" && !inPlace {
+		return cli.NewExitError("--backup requires --in-place", CodeBadArgument)
 	}
-	node, ok := m[s]
-	if !ok {
-		return true
+	if inPlace && explain {
+		return cli.NewExitError("--explain can't be used with --in-place", CodeBadArgument)
 	}
-	if node.Whitelist {
-		return true
+	if inPlace && c.IsSet("env-prefix") {
+		return cli.NewExitError("--env-prefix can't be used with --in-place", CodeBadArgument)
 	}
-	return !node.Blacklist
-}
-
-func (m Mode) Define(s string) bool {
-	if _, ok := m[s]; ok {
-		return false
+	if output != "" && (inPlace || explain) {
+		return cli.NewExitError("--output can't be used with --in-place or --explain", CodeBadArgument)
 	}
-	m[s] = &Node{
-		Path: s,
+	if check && output == "" && !inPlace {
+		return cli.NewExitError("--check requires --output or --in-place", CodeBadArgument)
+	}
+	if c.Bool("semantic") && !check {
+		return cli.NewExitError("--semantic requires --check", CodeBadArgument)
 	}
-	return true
-}
-
-func (m Mode) merge(a, b interface{}, path []string) interface{} {
-	switch tB := b.(type) {
-	case map[string]interface{}:
-		result := make(map[string]interface{})
-		tA, _ := a.(map[string]interface{})
-
-		if tA != nil {
-			for k, vA := range tA {
-				newPath := append(path, k)
-
-				if !m.Included(strings.Join(newPath, ".")) {
-					continue
-				}
 
-				result[k] = m.merge(nil, vA, newPath)
-			}
+	var q *query.Query
+	if s := c.String("query"); s != "" {
+		if inPlace || explain {
+			return cli.NewExitError("--query can't be used with --in-place or --explain", CodeBadArgument)
 		}
-		if tB != nil {
-			for k, vB := range tB {
-				newPath := append(path, k)
-
-				if !m.Included(strings.Join(newPath, ".")) {
-					continue
-				}
-
-				vA, _ := result[k]
-
-				result[k] = m.merge(vA, vB, newPath)
+		var err error
+		if q, err = query.Parse(s); err != nil {
+			if err, ok := err.(*query.SyntaxError); ok {
+				return cli.NewExitError(fmt.Sprintf("invalid --query at %d: %s\n%s", err.Offset, err.Message, err.Caret()), CodeBadArgument)
 			}
+			return cli.NewExitError(fmt.Sprintf("invalid --query: %s", err.Error()), CodeBadArgument)
 		}
+	}
 
-		return result
-
-	case []interface{}:
-		result := make([]interface{}, 0)
-		tA, _ := a.([]interface{})
-
-		if tA != nil {
-			for i, vA := range tA {
-				newPath := append(path, strconv.Itoa(i))
-
-				if !m.Included(strings.Join(newPath, ".")) {
-					continue
-				}
-
-				if i == len(result) {
-					// append case
-					result = append(result, m.merge(nil, vA, newPath))
-					continue
-				}
-
-				if i >= len(result) {
-					continue
-				}
-
-				result[i] = m.merge(nil, vA, newPath)
-			}
+	var configSchema *schema.Schema
+	if p := c.String("schema"); p != "" {
+		var err error
+		if configSchema, err = loadSchema(appParser, appFormats, p); err != nil {
+			return err
 		}
-		if tB != nil {
-			for i, vB := range tB {
-				newPath := append(path, strconv.Itoa(i))
+	}
 
-				if !m.Included(strings.Join(newPath, ".")) {
-					continue
-				}
+	// handle options
+	if flag := c.String("format"); flag != "" {
+		if format, ok := appFormats[strings.ToLower(flag)]; ok {
+			targetFormat = format
+		} else {
+			return cli.NewExitError("unable to determine the format from: "+flag, CodeBadFormat)
+		}
+	}
 
-				if i == len(result) {
-					// append case
-					result = append(result, m.merge(nil, vB, newPath))
-					continue
-				}
+	p, err := newPipeline(c)
+	if err != nil {
+		return err
+	}
+	p.SourceLines = explain
 
-				if i >= len(result) {
-					continue
-				}
+	inputList, err := p.Inputs(c.Args())
+	if err != nil {
+		return err
+	}
 
-				result[i] = m.merge(nil, vB, newPath)
-			}
+	if inPlace {
+		switch {
+		case inputList[0].Stdin:
+			return cli.NewExitError("unable to edit stdin in place", CodeBadArgument)
+		case inputList[0].Patch:
+			return cli.NewExitError(fmt.Sprintf("unable to edit the json patch '%s' in place", inputList[0].Path), CodeBadArgument)
+		case inputList[0].Index >= 0:
+			return cli.NewExitError(fmt.Sprintf("unable to edit a single yaml document of '%s' in place", inputList[0].Path), CodeBadArgument)
+		case inputList[0].Expanded:
+			return cli.NewExitError(fmt.Sprintf("unable to edit '%s' in place, as it was found via a directory or glob pattern", inputList[0].Path), CodeBadArgument)
+		case targetFormat != parser.Auto && targetFormat != inputList[0].Format:
+			return cli.NewExitError(fmt.Sprintf("unable to convert '%s' in place", inputList[0].Path), CodeBadArgument)
 		}
+	}
 
-		return result
-
-	default:
-		return b
+	if targetFormat == parser.Auto && output != "" {
+		targetFormat, _ = formatFromPath(appFormats, output)
+	}
+	if targetFormat == parser.Auto {
+		targetFormat = defaultFormat(inputList)
 	}
-}
 
-func (m Mode) Merge(a, b interface{}) interface{} {
-	return m.merge(a, b, make([]string, 0))
-}
+	// merge, and apply options
+	data, mode, err := p.Merge(inputList)
+	if err != nil {
+		return err
+	}
 
-func appAction(c *cli.Context) error {
-	appFormats := AppFormats()
-	appParser := AppParser()
+	if configSchema != nil {
+		if err := validate(configSchema, mode, data); err != nil {
+			return err
+		}
+	}
 
-	inputList := make([]mergeTarget, 0)
-	args := c.Args()
-	var (
-		targetFormat parser.Format
-	)
+	if q != nil {
+		data = q.Apply(data)
+	}
 
-	// handle options
-	if flag := c.String("format"); flag != "" {
-		if format, ok := appFormats[strings.ToLower(flag)]; ok {
-			targetFormat = format
-		} else {
-			return cli.NewExitError("unable to determine the format from: "+flag, CodeBadFormat)
+	if explain {
+		if err := writeExplain(os.Stdout, mode.Explain(data)); err != nil {
+			return cli.NewExitError(fmt.Sprintf("unable to explain: %s", err.Error()), CodeWriteError)
 		}
+		return nil
 	}
 
-	// handle mode
-	mode := make(Mode)
-	for _, included := range c.StringSlice("whitelist") { // TODO: implement whitelist
-		mode.Define(included)
-		mode[included].Whitelist = true
-	}
-	for _, excluded := range c.StringSlice("blacklist") {
-		mode.Define(excluded)
-		mode[excluded].Blacklist = true
+	// print the combined output
+	buffer := bytes.NewBufferString("")
+	if err := appParser.Write(targetFormat, data, buffer); err != nil {
+		return cli.NewExitError(fmt.Sprintf("unable to output to format %v: %s", targetFormat, err.Error()), CodeWriteError)
 	}
 
-	// handle args
-	for i := 0; i < len(args); i++ {
-		var (
-			format parser.Format
-			flag   string
-			inc    = 1
-			ok     bool
-			index  int
-		)
+	if check {
+		target := output
+		if inPlace {
+			target = inputList[0].Path
+		}
+		diff, err := checkOutput(appParser, targetFormat, target, buffer.Bytes(), c.Bool("semantic"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("unable to check '%s': %s", target, err.Error()), CodeReadError)
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			return cli.NewExitError("", CodeCheckFailed)
+		}
+		return nil
+	}
 
-		// TODO: separator support
+	if output != "" {
+		if err := writeFileAtomic(output, buffer.Bytes()); err != nil {
+			return cli.NewExitError(fmt.Sprintf("unable to write '%s': %s", output, err.Error()), CodeWriteError)
+		}
+		return nil
+	}
 
-		// try to parse the format via a flag? e.g. --json file_path
-		if i < len(args)-1 {
-			if v := []rune(strings.ToLower(args[i])); len(v) > 2 && v[0] == '-' && v[1] == '-' {
-				flag = string(v[2:])
-				format, ok = appFormats[flag]
+	if inPlace {
+		target := inputList[0].Path
+		if backup != "" {
+			b, err := ioutil.ReadFile(target)
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("unable to read '%s': %s", target, err.Error()), CodeReadError)
 			}
-		}
-		if ok {
-			// found a flag, does it require any extra args?
-			switch flag {
-			case `yaml-index`, `yml-index`:
-				if i >= len(args)-2 {
-					ok = false
-				} else if v, err := strconv.Atoi(args[i+1]); err != nil || v < 0 {
-					return cli.NewExitError(fmt.Sprintf("invalid %s argument index: %s", args[i], args[i+1]), CodeBadArgument)
-				} else {
-					inc++
-					index = v
-				}
+			if err := writeFileAtomic(target+backup, b); err != nil {
+				return cli.NewExitError(fmt.Sprintf("unable to write backup '%s': %s", target+backup, err.Error()), CodeWriteError)
 			}
 		}
-		if !ok {
-			// parse the format via the file path?
-			ext := []rune(path.Ext(args[i]))
-			if len(ext) > 0 {
-				format, ok = appFormats[strings.ToLower(string(ext[1:]))]
-			}
-			if !ok {
-				return cli.NewExitError("unable to determine the format from: "+args[i], CodeBadFormat)
-			}
-		} else {
-			// successfully consumed a format, we can increment
-			i += inc
+		if err := writeFileAtomic(target, buffer.Bytes()); err != nil {
+			return cli.NewExitError(fmt.Sprintf("unable to write '%s': %s", target, err.Error()), CodeWriteError)
 		}
+		return nil
+	}
+
+	fmt.Print(buffer.String())
 
-		stats, err := os.Stat(args[i])
+	return nil
+}
+
+// formatFromPath determines the format from the file extension of p.
+func formatFromPath(appFormats map[string]parser.Format, p string) (parser.Format, bool) {
+	ext := []rune(path.Ext(p))
+	if len(ext) == 0 {
+		return parser.Auto, false
+	}
+	format, ok := appFormats[strings.ToLower(string(ext[1:]))]
+	return format, ok
+}
+
+// openTarget reads the config at p (or stdin), selecting a single yaml document if index is not negative.
+func openTarget(format parser.Format, p string, index int, stdin bool) (mergeTarget, error) {
+	var r io.Reader
+	if stdin {
+		r = os.Stdin
+	} else {
+		stats, err := os.Stat(p)
 		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("unable to read '%s': %s", args[i], err.Error()), CodeReadError)
+			return mergeTarget{}, cli.NewExitError(fmt.Sprintf("unable to read '%s': %s", p, err.Error()), CodeReadError)
 		}
 
 		if stats.IsDir() {
-			return cli.NewExitError(fmt.Sprintf("unable to merge directory '%s'", args[i]), CodeReadError)
+			return mergeTarget{}, cli.NewExitError(fmt.Sprintf("unable to merge directory '%s'", p), CodeReadError)
 		}
 
-		var r io.Reader
-		if f, err := os.Open(args[i]); err != nil {
-			return cli.NewExitError(fmt.Sprintf("unable to open '%s': %s", args[i], err.Error()), CodeReadError)
-		} else {
-			//noinspection GoDeferInLoop
-			defer f.Close()
-			r = f
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return mergeTarget{}, cli.NewExitError(fmt.Sprintf("unable to open '%s': %s", p, err.Error()), CodeReadError)
 		}
+		r = bytes.NewReader(b)
+	}
 
-		// read any skipped yaml items from the stream - ghetto as hell but whatever
-		if index > 0 {
-			var (
-				d = yaml.NewDecoder(r)
-				v interface{}
-			)
-			for x := 0; x <= index; x++ {
-				if err := d.Decode(&v); err != nil {
-					return cli.NewExitError(fmt.Sprintf("unable to decode yaml at %d of '%s': %s", x, args[i], err.Error()), CodeReadError)
-				}
-			}
-			b, err := yaml.Marshal(v)
-			if err != nil {
-				return cli.NewExitError(fmt.Sprintf("unable to encode yaml at %d of '%s': %s", index, args[i], err.Error()), CodeReadError)
+	// read any skipped yaml items from the stream - ghetto as hell but whatever
+	if index > 0 {
+		var (
+			d = yaml.NewDecoder(r)
+			v interface{}
+		)
+		for x := 0; x <= index; x++ {
+			if err := d.Decode(&v); err != nil {
+				return mergeTarget{}, cli.NewExitError(fmt.Sprintf("unable to decode yaml at %d of '%s': %s", x, p, err.Error()), CodeReadError)
 			}
-			r = bytes.NewBuffer(b)
 		}
-
-		inputList = append(inputList, mergeTarget{format, r})
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return mergeTarget{}, cli.NewExitError(fmt.Sprintf("unable to encode yaml at %d of '%s': %s", index, p, err.Error()), CodeReadError)
+		}
+		r = bytes.NewBuffer(b)
 	}
 
-	if len(inputList) <= 0 {
-		return cli.NewExitError("at least one target config must be provided", CodeNoTargets)
+	return mergeTarget{format, r, p, stdin, index, false, false}, nil
+}
+
+// writeFileAtomic replaces the file at name (following symlinks) with data, via a rename of a temporary file in the
+// same directory, keeping the mode of any existing file.
+func writeFileAtomic(name string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(name); err == nil {
+		name = resolved
 	}
 
-	if targetFormat == parser.Auto {
-		targetFormat = inputList[0].Format
+	mode := os.FileMode(0644)
+	if stats, err := os.Stat(name); err == nil {
+		mode = stats.Mode().Perm()
 	}
 
-	// merge, and apply options
-	var data interface{}
-	for _, input := range inputList {
-		// read the file
-		newData, err := appParser.Read(input.Format, input.Reader)
-		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("unable to parse file format %v: %s", input.Format, err.Error()), CodeReadError)
-		}
-		data = mode.Merge(data, newData)
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
 	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
 
-	// print the combined output
-	buffer := bytes.NewBufferString("")
-	if err := appParser.Write(targetFormat, data, buffer); err != nil {
-		return cli.NewExitError(fmt.Sprintf("unable to output to format %v: %s", targetFormat, err.Error()), CodeWriteError)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return err
 	}
-	fmt.Print(buffer.String())
 
-	return nil
+	return os.Rename(tmp, name)
+}
+
+func appCommands() []cli.Command {
+	return []cli.Command{
+		getCommand(),
+		diffCommand(),
+		schemaCommand(),
+		codegenCommand(),
+	}
 }
 
 func appFlags() []cli.Flag {
-	return []cli.Flag{
+	flags := append([]cli.Flag{formatFlag()}, mergeFlags()...)
+	return append(
+		flags,
+		cli.StringFlag{
+			Name:  "query,q",
+			Usage: "output the result of a query (a JSONPath subset) over the merged result, e.g. services[?@.enabled == true].image, where a query of only names and indexes outputs a single value, and anything else an array",
+		},
 		cli.StringFlag{
-			Name:  "format,f",
-			Usage: "target format for the output, one of (json, yaml, env, yml, env-simple)",
+			Name:  "schema",
+			Usage: "validate the merged result against this JSON Schema (in any supported format), printing every violation and exiting with code 20 if it is invalid",
 		},
+		cli.BoolFlag{
+			Name:  "explain",
+			Usage: "instead of the output, print a table of every leaf path, its value, the CONFIG (and line, for json, yaml, and env) that set it, and any it overrode",
+		},
+		cli.BoolFlag{
+			Name:  "in-place",
+			Usage: "write the output back to the first CONFIG, in its own format, instead of printing it",
+		},
+		cli.StringFlag{
+			Name:  "backup",
+			Usage: "with --in-place, keep a copy of the original first CONFIG at its path plus this suffix",
+		},
+		cli.StringFlag{
+			Name:  "output,o",
+			Usage: "write the output to this file, instead of printing it, where the format defaults to its extension",
+		},
+		cli.BoolFlag{
+			Name:  "check",
+			Usage: "with --output or --in-place, compare the output to the existing file instead of writing it, printing a diff and exiting with code 19 if they differ",
+		},
+		cli.BoolFlag{
+			Name:  "semantic",
+			Usage: "with --check, compare the parsed values rather than the bytes, ignoring formatting and key order",
+		},
+	)
+}
+
+func formatFlag() cli.Flag {
+	return cli.StringFlag{
+		Name:  "format,f",
+		Usage: "target format for the output, one of (json, yaml, env, yml, env-simple)",
+	}
+}
+
+// mergeFlags are the options shared by every command that merges CONFIGs, see newPipeline.
+func mergeFlags() []cli.Flag {
+	overrides := new([]override)
+	return []cli.Flag{
 		cli.StringSliceFlag{
 			Name:  "whitelist,include,i,w",
-			Usage: "whitelisted paths (dot notation) will always be included",
+			Usage: "if provided, only whitelisted paths (e.g. a.b[0][\"c.d\"], supporting * and ** wildcards) will be included, along with their ancestors (if anything within them is)",
 		},
 		cli.StringSliceFlag{
 			Name:  "blacklist,excluded,e,b",
-			Usage: "blacklisted paths (dot notation) will be excluded unless whitelisted",
+			Usage: "blacklisted paths (e.g. a.b[0][\"c.d\"], supporting * and ** wildcards) will be excluded, unless a more specific path is whitelisted",
+		},
+		cli.StringSliceFlag{
+			Name:  "array-strategy",
+			Usage: "how arrays are merged, either STRATEGY to set the default, or PATH=STRATEGY, one of (index-merge, replace, append, prepend, unique-union, merge-by-key:KEY)",
+		},
+		cli.StringFlag{
+			Name:  "array-exclusion",
+			Value: "compact",
+			Usage: "how excluded array elements are handled, one of (compact, preserve), where preserve replaces them with null, keeping the positions of the other elements",
+		},
+		cli.BoolFlag{
+			Name:  "merge-patch",
+			Usage: "merge using JSON Merge Patch (RFC 7386) semantics, where null removes a key, and arrays are replaced by default",
+		},
+		cli.StringFlag{
+			Name:  "conflict",
+			Value: "overwrite",
+			Usage: "how to merge a value of a different kind (object, array, or scalar) to the base, one of (overwrite, error, keep-base)",
+		},
+		cli.BoolFlag{
+			Name:  "conflict-report",
+			Usage: "print a warning to stderr for every change in the kind of a value, see --conflict",
+		},
+		cli.GenericFlag{
+			Name:  "set",
+			Value: &overrideFlag{Name: "set", List: overrides},
+			Usage: "set PATH=VALUE, after merging the CONFIGs, where VALUE is parsed as a yaml scalar, creating any missing objects, or arrays, for indexes (e.g. a.b[0].c=1), with every override applied in order",
+		},
+		cli.GenericFlag{
+			Name:  "set-string",
+			Value: &overrideFlag{Name: "set-string", List: overrides},
+			Usage: "like --set, but VALUE is always a string",
+		},
+		cli.GenericFlag{
+			Name:  "set-json",
+			Value: &overrideFlag{Name: "set-json", List: overrides},
+			Usage: "like --set, but VALUE is parsed as json",
+		},
+		cli.GenericFlag{
+			Name:  "unset",
+			Value: &overrideFlag{Name: "unset", List: overrides},
+			Usage: "remove PATH, after merging the CONFIGs, see --set",
+		},
+		cli.StringFlag{
+			Name:  "env-prefix",
+			Usage: "merge environment variables starting with this prefix over the CONFIGs (before any --set), e.g. with APP_, APP_DB__HOST sets db.host, where values are converted to the type of any existing value",
+		},
+		cli.StringFlag{
+			Name:  "env-separator",
+			Value: "__",
+			Usage: "with --env-prefix, the separator for nested keys within variable names",
+		},
+		cli.StringFlag{
+			Name:  "env-case",
+			Value: "lower",
+			Usage: "with --env-prefix, how variable names are folded into keys that don't match an existing key (case insensitively), one of (lower, upper, preserve)",
+		},
+		cli.BoolFlag{
+			Name:  "recursive,r",
+			Usage: "include the files of nested directories, when a CONFIG is a directory",
+		},
+		cli.StringSliceFlag{
+			Name:  "file-include",
+			Usage: "only merge files from directories and globs matching these patterns (base name, or relative path if it contains a slash)",
+		},
+		cli.StringSliceFlag{
+			Name:  "file-exclude",
+			Usage: "skip files from directories and globs matching these patterns (base name, or relative path if it contains a slash)",
+		},
+		cli.StringFlag{
+			Name:  "unknown-ext",
+			Value: "fail",
+			Usage: "how to handle files from directories and globs without a known extension, one of (fail, skip)",
 		},
 	}
 }
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"github.com/go-test/deep"
+	"github.com/joeycumines/go-configger/parser"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 	"testing"
 )
 
 type testCase struct {
 	Args     []string
+	Dir      string
+	Stdin    string
+	Env      []string
 	Expected string
 	Code     int
 }
@@ -116,186 +122,365 @@ unique_yaml: 14.64
 				pkgPath + `/testdata/example.yaml`,
 			},
 			Expected: `{
+  "nested": {
+    "more": [
+      0.1
+    ]
+  }
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-e`,
+				`nested.*`,
+				`-e`,
+				`array.[!1]`,
+				`-e`,
+				`**_yaml`,
+				pkgPath + `/testdata/example.json`,
+				pkgPath + `/testdata/example.yaml`,
+			},
+			Expected: `{
   "array": [
-    11,
     22
   ],
+  "nested": {},
   "unique": true
 }`,
 			Code: 0,
 		},
 		{
 			Args: []string{
-				pkgPath + `/testdata/simple.env`,
-				pkgPath + `/testdata/simple.json`,
-				pkgPath + `/testdata/simple.yml`,
+				`-e`,
+				`nested.[a`,
+				pkgPath + `/testdata/example.json`,
 			},
-			Expected: `four=34
-one=11
-three=33
-two=22`,
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`-e`,
+				`hosts["example.com"]`,
+				`-e`,
+				`labels.k8s\.io/name`,
+				`-e`,
+				`list[1][0]`,
+				pkgPath + `/testdata/dotted.json`,
+			},
+			Expected: `{
+  "hosts": {
+    "example": {
+      "com": {
+        "port": 8080
+      }
+    }
+  },
+  "labels": {
+    "k8s": {
+      "io/name": "b"
+    }
+  },
+  "list": [
+    [
+      1,
+      2
+    ],
+    [
+      4
+    ]
+  ]
+}`,
 			Code: 0,
 		},
 		{
 			Args: []string{
-				pkgPath + `/testdata/simple-noend.env-simple`,
-				pkgPath + `/testdata/simple.json`,
-				pkgPath + `/testdata/simple.yml`,
+				`-i`,
+				`hosts.*['port']`,
+				`-i`,
+				`labels['k8s.io/name']`,
+				pkgPath + `/testdata/dotted.json`,
 			},
-			Expected: `four=34
-one=11
-three=33
-two=22
-`,
+			Expected: `{
+  "hosts": {
+    "example.com": {
+      "port": 80
+    }
+  },
+  "labels": {
+    "k8s.io/name": "a"
+  }
+}`,
 			Code: 0,
 		},
 		{
-			Args:     []string{},
+			Args: []string{
+				`-i`,
+				`hosts..port`,
+				pkgPath + `/testdata/dotted.json`,
+			},
 			Expected: ``,
-			Code:     CodeNoTargets,
+			Code:     CodeBadArgument,
 		},
 		{
 			Args: []string{
-				`--`,
-				`--yaml-index`,
-				`0`,
-				pkgPath + `/testdata/example.yaml`,
-				pkgPath + `/testdata/example.json`,
+				`--array-strategy`,
+				`allowed_hosts=replace`,
+				`--array-strategy`,
+				`plugins=append`,
+				`--array-strategy`,
+				`tags=unique-union`,
+				pkgPath + `/testdata/arrays-base.yaml`,
+				pkgPath + `/testdata/arrays-overlay.yaml`,
 			},
-			Expected: `array:
-- 1
+			Expected: `allowed_hosts:
+- c
+matrix:
+- 10
 - 2
 - 3
-nested:
-  another: {}
-  more:
-  - 0.1
-  - 0.2
-  overridden: 9.5
-unique: true
-unique_yaml: 14.64
+plugins:
+- p1
+- p2
+- p3
+tags:
+- t1
+- t2
+- t3
 `,
 			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yamL-index`,
-				`0`,
+				`--array-strategy`,
+				`prepend`,
+				`--array-strategy`,
+				`matrix=replace`,
+				pkgPath + `/testdata/arrays-base.yaml`,
+				pkgPath + `/testdata/arrays-overlay.yaml`,
 			},
-			Expected: ``,
-			Code:     CodeBadFormat,
+			Expected: `allowed_hosts:
+- c
+- a
+- b
+matrix:
+- 10
+plugins:
+- p3
+- p1
+- p2
+tags:
+- t2
+- t3
+- t1
+- t2
+`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--ymL-index`,
-				`0`,
+				`--array-strategy`,
+				`tags=union`,
+				pkgPath + `/testdata/arrays-base.yaml`,
 			},
 			Expected: ``,
-			Code:     CodeBadFormat,
+			Code:     CodeBadArgument,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`-1`,
-				pkgPath + `/testdata/multi.yml`,
+				`--array-strategy`,
+				`merge-by-key:name`,
+				`--array-strategy`,
+				`containers[*].env=append`,
+				pkgPath + `/testdata/deployment.yaml`,
+				pkgPath + `/testdata/deployment-prod.yaml`,
 			},
-			Expected: ``,
-			Code:     CodeBadArgument,
+			Expected: `containers:
+- env:
+  - name: LOG_LEVEL
+    value: info
+  - name: PORT
+    value: "8080"
+  - name: LOG_LEVEL
+    value: warn
+  - name: REGION
+    value: ap-southeast-2
+  image: app:1.1
+  name: app
+- image: proxy:1.0
+  name: sidecar
+- image: metrics:2.0
+  name: metrics
+volumes:
+- emptyDir: {}
+  name: data
+- configMap:
+    name: app-config
+  name: config
+`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				``,
-				pkgPath + `/testdata/multi.yml`,
+				`--array-strategy`,
+				`containers=merge-by-key:name`,
+				`--array-strategy`,
+				`containers[*].env=merge-by-key:name`,
+				pkgPath + `/testdata/deployment.yaml`,
+				pkgPath + `/testdata/deployment-prod.yaml`,
+			},
+			Expected: `containers:
+- env:
+  - name: LOG_LEVEL
+    value: warn
+  - name: PORT
+    value: "8080"
+  - name: REGION
+    value: ap-southeast-2
+  image: app:1.1
+  name: app
+- image: proxy:1.0
+  name: sidecar
+- image: metrics:2.0
+  name: metrics
+volumes:
+- configMap:
+    name: app-config
+  name: config
+`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`--array-strategy`,
+				`containers=merge-by-key`,
+				pkgPath + `/testdata/deployment.yaml`,
 			},
 			Expected: ``,
 			Code:     CodeBadArgument,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`0`,
-				pkgPath + `/testdata/multi.yml`,
+				`--merge-patch`,
+				pkgPath + `/testdata/patch-base.json`,
+				pkgPath + `/testdata/patch-overlay.json`,
 			},
-			Expected: `0`,
-			Code:     0,
+			Expected: `{
+  "a": {
+    "c": null,
+    "f": {}
+  },
+  "d": [
+    3
+  ]
+}`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yml-index`,
-				`1`,
-				pkgPath + `/testdata/multi.yml`,
+				`--explain`,
+				`example.json`,
+				`example.yaml`,
+				`--json-patch`,
+				`explain-ops.json`,
 			},
-			Expected: `1`,
-			Code:     0,
+			Dir: pkgPath + `/testdata`,
+			Expected: `PATH               VALUE        SOURCE            OVERRIDES
+array[0]           11           example.yaml:3    example.json:3
+array[1]           22           example.yaml:4    example.json:4
+array[2]           3            example.json:5
+nested.more[0]     0.1          example.json:10
+nested.more[1]     0.2          example.json:10
+nested.overridden  "something"  example.yaml:6    example.json:9
+unique             false        explain-ops.json  example.json:7
+unique_yaml        14.64        example.yaml:1
+`,
+			Code: 0,
 		},
 		{
 			Args: []string{
 				`-f`,
 				`json`,
-				`--`,
-				`--yamL-index`,
-				`2`,
-				pkgPath + `/testdata/multi.yml`,
+				`-e`,
+				`matrix[0]`,
+				pkgPath + `/testdata/arrays-base.yaml`,
+				pkgPath + `/testdata/arrays-overlay.yaml`,
 			},
-			Expected: `"2"`,
-			Code:     0,
+			Expected: `{
+  "allowed_hosts": [
+    "c",
+    "b"
+  ],
+  "matrix": [
+    2,
+    3
+  ],
+  "plugins": [
+    "p3",
+    "p2"
+  ],
+  "tags": [
+    "t2",
+    "t3"
+  ]
+}`,
+			Code: 0,
 		},
 		{
 			Args: []string{
 				`-f`,
 				`json`,
-				`--`,
-				`--ymL-index`,
-				`3`,
-				pkgPath + `/testdata/multi.yml`,
+				`--array-exclusion`,
+				`preserve`,
+				`-e`,
+				`matrix[0]`,
+				pkgPath + `/testdata/arrays-base.yaml`,
+				pkgPath + `/testdata/arrays-overlay.yaml`,
 			},
-			Expected: `"Three!"`,
-			Code:     0,
+			Expected: `{
+  "allowed_hosts": [
+    "c",
+    "b"
+  ],
+  "matrix": [
+    null,
+    2,
+    3
+  ],
+  "plugins": [
+    "p3",
+    "p2"
+  ],
+  "tags": [
+    "t2",
+    "t3"
+  ]
+}`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`4`,
-				pkgPath + `/testdata/multi.yml`,
+				`--array-exclusion`,
+				`drop`,
+				pkgPath + `/testdata/arrays-base.yaml`,
 			},
-			Expected: `[
-  4
-]`,
-			Code: 0,
+			Expected: ``,
+			Code:     CodeBadArgument,
 		},
 		{
 			Args: []string{
 				`-f`,
 				`json`,
-				`--`,
-				`--yaml-index`,
-				`5`,
-				pkgPath + `/testdata/multi.yml`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+				pkgPath + `/testdata/conflict-overlay.yaml`,
 			},
 			Expected: `{
-  "five": null
+  "db": "postgres://y:5432",
+  "hosts": {
+    "a": 1
+  },
+  "name": "overlay"
 }`,
 			Code: 0,
 		},
@@ -303,83 +488,1283 @@ unique_yaml: 14.64
 			Args: []string{
 				`-f`,
 				`json`,
-				`--`,
-				`--yaml-index`,
-				`6`,
-				pkgPath + `/testdata/multi.yml`,
+				`--conflict`,
+				`keep-base`,
+				`--conflict-report`,
+				`conflict-base.yaml`,
+				`conflict-overlay.yaml`,
 			},
-			Expected: `null`,
-			Code:     0,
+			Dir: pkgPath + `/testdata`,
+			Expected: `warning: conflict at db: kept object (from conflict-base.yaml), ignoring scalar (from conflict-overlay.yaml)
+warning: conflict at hosts: kept array (from conflict-base.yaml), ignoring object (from conflict-overlay.yaml)
+{
+  "db": {
+    "host": "x",
+    "port": 5432
+  },
+  "hosts": [
+    "a",
+    "b"
+  ],
+  "name": "overlay"
+}`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`7`,
-				pkgPath + `/testdata/multi.yml`,
+				`--conflict`,
+				`error`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+				pkgPath + `/testdata/conflict-overlay.yaml`,
 			},
-			Expected: `true`,
-			Code:     0,
+			Expected: ``,
+			Code:     CodeConflict,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`8`,
-				pkgPath + `/testdata/multi.yml`,
+				`--conflict`,
+				`error`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+				pkgPath + `/testdata/conflict-base.yaml`,
 			},
-			Expected: `null`,
-			Code:     0,
+			Expected: `db:
+  host: x
+  port: 5432
+hosts:
+- a
+- b
+name: base
+`,
+			Code: 0,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
-				`--`,
-				`--yaml-index`,
-				`9`,
-				pkgPath + `/testdata/multi.yml`,
+				`--conflict`,
+				`ignore`,
+				pkgPath + `/testdata/conflict-base.yaml`,
 			},
-			Expected: `null`,
-			Code:     0,
+			Expected: ``,
+			Code:     CodeBadArgument,
 		},
 		{
 			Args: []string{
-				`-f`,
-				`json`,
+				pkgPath + `/testdata/patch-base.json`,
+				`--json-patch`,
+				pkgPath + `/testdata/ops.json`,
+				`--patch`,
+				pkgPath + `/testdata/ops.yaml`,
+			},
+			Expected: `{
+  "a": {
+    "e": "x"
+  },
+  "b": 1,
+  "d": [
+    1,
+    5,
+    2,
+    9
+  ],
+  "e": "y-replaced"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-b`,
+				`a`,
+				pkgPath + `/testdata/patch-base.json`,
+				`--yaml-patch`,
+				pkgPath + `/testdata/ops.yaml`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Expected: `{
+  "d": [
+    1,
+    2,
+    9
+  ],
+  "e": "y-replaced",
+  "three": 23,
+  "two": 22
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				pkgPath + `/testdata/patch-base.json`,
+				`--patch`,
+				pkgPath + `/testdata/ops-fail.yaml`,
+			},
+			Expected: ``,
+			Code:     CodePatchError,
+		},
+		{
+			Args: []string{
+				`--in-place`,
 				`--`,
-				`--yaml-index`,
-				`10`,
-				pkgPath + `/testdata/multi.yml`,
+				`--json-patch`,
+				pkgPath + `/testdata/ops.json`,
+				pkgPath + `/testdata/patch-base.json`,
 			},
 			Expected: ``,
-			Code:     CodeReadError,
+			Code:     CodeBadArgument,
 		},
-	}
-
-	var bin string
-	func() {
-		dir, err := ioutil.TempDir(``, ``)
-		if err != nil {
-			t.Fatal(err)
-		}
-		bin = filepath.Join(dir, `goconfigger.exe`)
-		cmd := exec.Command(`go`, `build`, `-v`, `-o`, bin, pkgPath)
-		cmd.Dir = pkgPath
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			t.Fatal(err)
-		}
-	}()
-	defer os.Remove(bin)
-
-	for _, testCase := range testCases {
+		{
+			Args: []string{
+				pkgPath + `/testdata/patch-base.json`,
+				pkgPath + `/testdata/patch-overlay.json`,
+			},
+			Expected: `{
+  "a": {
+    "b": null,
+    "c": null,
+    "f": {
+      "g": null
+    }
+  },
+  "d": [
+    3,
+    2
+  ],
+  "e": null
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				pkgPath + `/testdata/simple.env`,
+				pkgPath + `/testdata/simple.json`,
+				pkgPath + `/testdata/simple.yml`,
+			},
+			Expected: `four=34
+one=11
+three=33
+two=22`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				pkgPath + `/testdata/simple-noend.env-simple`,
+				pkgPath + `/testdata/simple.json`,
+				pkgPath + `/testdata/simple.yml`,
+			},
+			Expected: `four=34
+one=11
+three=33
+two=22
+`,
+			Code: 0,
+		},
+		{
+			Args:     []string{},
+			Expected: ``,
+			Code:     CodeNoTargets,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--json`,
+				`-`,
+				pkgPath + `/testdata/simple.yml`,
+			},
+			Stdin: `{"three": 3, "five": 5}`,
+			Expected: `{
+  "five": 5,
+  "four": 34,
+  "three": 33
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-`,
+			},
+			Stdin:    `{}`,
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`--in-place`,
+				`--`,
+				`--json`,
+				`-`,
+			},
+			Stdin:    `{}`,
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--in-place`,
+				`--`,
+				`--yaml-index`,
+				`0`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--in-place`,
+				`--unknown-ext`,
+				`skip`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--check`,
+				`--in-place`,
+				`--unknown-ext`,
+				`skip`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--unknown-ext`,
+				`skip`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: `{
+  "level": "99",
+  "name": "base",
+  "region": "ap-southeast-2"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--file-exclude`,
+				`*.txt`,
+				`--file-exclude`,
+				`*.env`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: `{
+  "level": 10,
+  "name": "base",
+  "region": "ap-southeast-2"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`-r`,
+				`--file-include`,
+				`*.yaml`,
+				`--file-include`,
+				`*.env`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: `{
+  "level": 50,
+  "name": "base",
+  "nested": true,
+  "region": "none"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`-r`,
+				`--file-exclude`,
+				`nested/*`,
+				`--unknown-ext`,
+				`skip`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: `{
+  "level": "99",
+  "name": "base",
+  "region": "ap-southeast-2"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--file-exclude`,
+				`nested/*.yaml`,
+				`--unknown-ext`,
+				`skip`,
+				pkgPath + `/testdata/conf.d/*`,
+			},
+			Expected: `{
+  "level": "99",
+  "name": "base",
+  "region": "ap-southeast-2"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--file-include`,
+				`nested/*`,
+				pkgPath + `/testdata/conf.d/*`,
+			},
+			Expected: `{
+  "level": 50,
+  "nested": true
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				pkgPath + `/testdata/conf.d/*.yaml`,
+				pkgPath + `/testdata/conf.d/1?-*`,
+			},
+			Expected: `{
+  "level": 10,
+  "name": "base",
+  "region": "ap-southeast-2"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				pkgPath + `/testdata/conf.d/**/*.yaml`,
+			},
+			Expected: `{
+  "level": 50,
+  "name": "base",
+  "nested": true,
+  "region": "none"
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml`,
+				pkgPath + `/testdata/conf.d/*/`,
+			},
+			Expected: `{
+  "level": 50,
+  "nested": true
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				pkgPath + `/testdata/conf.d/*.toml`,
+			},
+			Expected: ``,
+			Code:     CodeReadError,
+		},
+		{
+			Args: []string{
+				`--unknown-ext`,
+				`ignore`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--yaml-index`,
+				`0`,
+				pkgPath + `/testdata/conf.d`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--json`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--json`,
+				`--`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--yaml-index`,
+				`1`,
+				`--`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--json`,
+				`--`,
+				`--json`,
+			},
+			Dir: pkgPath + `/testdata`,
+			Expected: `{
+  "dashed": true
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yml-index`,
+				`1`,
+				`--`,
+				`multi.yml`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: `1`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`simple.json`,
+				`--json`,
+				`--`,
+				`--json`,
+			},
+			Dir: pkgPath + `/testdata`,
+			Expected: `{
+  "dashed": true,
+  "three": 23,
+  "two": 22
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--`,
+				`--yaml-index`,
+				`0`,
+				`multi.yml`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--json`,
+				`--`,
+				`-`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Stdin:    `{}`,
+			Expected: ``,
+			Code:     CodeReadError,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--`,
+			},
+			Expected: ``,
+			Code:     CodeNoTargets,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--json`,
+				`--`,
+				`--json`,
+				`--`,
+				`simple.yml`,
+			},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`--backup`,
+				`.bak`,
+				pkgPath + `/testdata/simple.yml`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--in-place`,
+				`-f`,
+				`json`,
+				pkgPath + `/testdata/simple.yml`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--`,
+				`--yaml-index`,
+				`0`,
+				pkgPath + `/testdata/example.yaml`,
+				pkgPath + `/testdata/example.json`,
+			},
+			Expected: `array:
+- 1
+- 2
+- 3
+nested:
+  another: {}
+  more:
+  - 0.1
+  - 0.2
+  overridden: 9.5
+unique: true
+unique_yaml: 14.64
+`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yamL-index`,
+				`0`,
+			},
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--ymL-index`,
+				`0`,
+			},
+			Expected: ``,
+			Code:     CodeBadFormat,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`-1`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				``,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: ``,
+			Code:     CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`0`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `0`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yml-index`,
+				`1`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `1`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yamL-index`,
+				`2`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `"2"`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--ymL-index`,
+				`3`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `"Three!"`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`4`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `[
+  4
+]`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`5`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `{
+  "five": null
+}`,
+			Code: 0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`6`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `null`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`7`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `true`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`8`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `null`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`9`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: `null`,
+			Code:     0,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--`,
+				`--yaml-index`,
+				`10`,
+				pkgPath + `/testdata/multi.yml`,
+			},
+			Expected: ``,
+			Code:     CodeReadError,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--env-prefix`,
+				`APP_`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Env: []string{
+				`APP_DB__PORT=6543`,
+				`APP_DB__HOST=db.internal`,
+				`APP_NAME=prod`,
+				`APP_HOSTS=["c"]`,
+				`APP_DEBUG=true`,
+				`APP_Tls__Enabled=1`,
+				`OTHER_NAME=other`,
+			},
+			Expected: `{
+  "db": {
+    "host": "db.internal",
+    "port": 6543
+  },
+  "debug": "true",
+  "hosts": [
+    "c",
+    "b"
+  ],
+  "name": "prod",
+  "tls": {
+    "enabled": "1"
+  }
+}`,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--env-prefix`,
+				`APP.`,
+				`--env-separator`,
+				`.`,
+				`--env-case`,
+				`upper`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Env: []string{
+				`APP.TWO=2`,
+				`APP.a.b=c`,
+			},
+			Expected: `{
+  "A": {
+    "B": "c"
+  },
+  "three": 23,
+  "two": 2
+}`,
+		},
+		{
+			Args: []string{
+				`--env-prefix`,
+				`APP_`,
+				`--env-case`,
+				`title`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--env-prefix`,
+				``,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Env:  []string{`HOME=/h`},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--env-prefix`,
+				`APP_`,
+				`--conflict`,
+				`error`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Env:  []string{`APP_NAME__FIRST=a`},
+			Code: CodeConflict,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--env-prefix`,
+				`APP_`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Env: []string{`APP_HOSTS__1=c`},
+			Expected: `{
+  "db": {
+    "host": "x",
+    "port": 5432
+  },
+  "hosts": [
+    "a",
+    "c"
+  ],
+  "name": "base"
+}`,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--env-prefix`,
+				`APP_`,
+				`--array-strategy`,
+				`append`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Env: []string{`APP_HOSTS__1=c`},
+			Expected: `{
+  "db": {
+    "host": "x",
+    "port": 5432
+  },
+  "hosts": [
+    "a",
+    "c"
+  ],
+  "name": "base"
+}`,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--set`,
+				`db.port=6543`,
+				`--set`,
+				`hosts[3]=~`,
+				`--set-string`,
+				`tls.enabled=true`,
+				`--set-json`,
+				`db.options={"ssl": false}`,
+				`--unset`,
+				`hosts[0]`,
+				`--set`,
+				`["a=b"].c=off`,
+				`--set`,
+				`name=a`,
+				`--unset`,
+				`name`,
+				`--set`,
+				`name=b=c`,
+				`--env-prefix`,
+				`APP_`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Env: []string{
+				`APP_NAME=env`,
+				`APP_DB__HOST=env`,
+			},
+			Expected: `{
+  "a=b": {
+    "c": false
+  },
+  "db": {
+    "host": "env",
+    "options": {
+      "ssl": false
+    },
+    "port": 6543
+  },
+  "hosts": [
+    "b",
+    null,
+    null
+  ],
+  "name": "b=c",
+  "tls": {
+    "enabled": "true"
+  }
+}`,
+		},
+		{
+			Args: []string{
+				`--set`,
+				`a[*]=1`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`-f`,
+				`json`,
+				`--set`,
+				`hosts.0=c`,
+				`--unset`,
+				`hosts.1`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Expected: `{
+  "db": {
+    "host": "x",
+    "port": 5432
+  },
+  "hosts": [
+    "c"
+  ],
+  "name": "base"
+}`,
+		},
+		{
+			Args: []string{
+				`--set`,
+				`hosts[99999999999]=1`,
+				pkgPath + `/testdata/conflict-base.yaml`,
+			},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--set-json`,
+				`a={`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{
+				`--unset`,
+				`a..b`,
+				pkgPath + `/testdata/simple.json`,
+			},
+			Code: CodeBadArgument,
+		},
+		{
+			Args:     []string{`get`, `db`, `conflict-base.yaml`, `conflict-overlay.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "postgres://y:5432\n",
+		},
+		{
+			Args:     []string{`get`, `name`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "base\n",
+		},
+		{
+			Args:     []string{`get`, `--set`, `a.b=true`, `a`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "b: true\n",
+		},
+		{
+			Args:     []string{`get`, `-f`, `json`, `hosts`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "[\n  \"a\",\n  \"b\"\n]",
+		},
+		{
+			Args:     []string{`get`, `hosts.0`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "a\n",
+		},
+		{
+			Args:     []string{`get`, `hosts[1]`, `--json`, `-`},
+			Stdin:    `{"hosts": ["a", null]}`,
+			Expected: "null\n",
+		},
+		{
+			Args:     []string{`get`, `--default`, `fallback`, `db.user`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "fallback\n",
+		},
+		{
+			Args: []string{`get`, `db.user`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeNotFound,
+		},
+		{
+			Args: []string{`get`, `db.*`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args:     []string{`diff`, `example.json`, `example.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "~ array[0]: 1 -> 11\n~ array[1]: 2 -> 22\n- array[2]: 3\n+ nested.another: {}\n- nested.more: [0.1,0.2]\n~ nested.overridden: 9.5 -> \"something\"\n- unique: true\n+ unique_yaml: 14.64\n",
+			Code:     CodeDiffers,
+		},
+		{
+			Args:     []string{`diff`, `example.json`, `--to`, `example.json`, `example.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "~ array[0]: 1 -> 11\n~ array[1]: 2 -> 22\n+ nested.another: {}\n~ nested.overridden: 9.5 -> \"something\"\n+ unique_yaml: 14.64\n",
+			Code:     CodeDiffers,
+		},
+		{
+			Args:     []string{`diff`, `-f`, `json`, `simple.json`, `simple.yml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "[\n  {\n    \"new\": 34,\n    \"path\": \"four\",\n    \"type\": \"added\"\n  },\n  {\n    \"new\": 33,\n    \"old\": 23,\n    \"path\": \"three\",\n    \"type\": \"changed\"\n  },\n  {\n    \"old\": 22,\n    \"path\": \"two\",\n    \"type\": \"removed\"\n  }\n]",
+			Code:     CodeDiffers,
+		},
+		{
+			Args:     []string{`diff`, `-f`, `json-patch`, `simple.json`, `simple.yml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "[\n  {\n    \"op\": \"add\",\n    \"path\": \"/four\",\n    \"value\": 34\n  },\n  {\n    \"op\": \"replace\",\n    \"path\": \"/three\",\n    \"value\": 33\n  },\n  {\n    \"op\": \"remove\",\n    \"path\": \"/two\"\n  }\n]",
+			Code:     CodeDiffers,
+		},
+		{
+			Args:     []string{`diff`, `-f`, `merge-patch`, `simple.json`, `simple.yml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "{\n  \"four\": 34,\n  \"three\": 33,\n  \"two\": null\n}",
+			Code:     CodeDiffers,
+		},
+		{
+			Args:     []string{`diff`, `--set`, `two=22`, `simple.json`, `--json`, `-`},
+			Dir:      pkgPath + `/testdata`,
+			Stdin:    `{"three": 23}`,
+			Expected: ``,
+		},
+		{
+			Args:     []string{`diff`, `-f`, `json`, `simple.json`, `simple.json`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: `[]`,
+		},
+		{
+			Args: []string{`diff`, `simple.json`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`diff`, `-f`, `yaml`, `simple.json`, `simple.yml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`diff`, `simple.json`, `--to`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeNoTargets,
+		},
+		{
+			Args:     []string{`--check`, `-o`, `simple.json`, `simple.json`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "--- simple.json\n+++ simple.json (expected)\n@@ -1,4 +1,4 @@\n {\n-  \"two\": 22,\n-  \"three\": 23\n+  \"three\": 23,\n+  \"two\": 22\n }\n\\ No newline at end of file\n",
+			Code:     CodeCheckFailed,
+		},
+		{
+			Args:     []string{`--check`, `--semantic`, `-o`, `simple.json`, `simple.json`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: ``,
+		},
+		{
+			Args:     []string{`--check`, `--semantic`, `-o`, `simple.json`, `simple.yml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "+ four: 34\n~ three: 23 -> 33\n- two: 22\n",
+			Code:     CodeCheckFailed,
+		},
+		{
+			Args:     []string{`--check`, `-o`, `missing.json`, `simple.json`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "missing.json doesn't exist\n",
+			Code:     CodeCheckFailed,
+		},
+		{
+			Args: []string{`--check`, pkgPath + `/testdata/simple.json`},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`--semantic`, `-o`, `out.json`, pkgPath + `/testdata/simple.json`},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`--explain`, `-o`, `out.json`, pkgPath + `/testdata/simple.json`},
+			Code: CodeBadArgument,
+		},
+		{
+			Args:     []string{`--schema`, `schema.yaml`, `-f`, `json`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "{\n  \"db\": {\n    \"host\": \"x\",\n    \"port\": 5432\n  },\n  \"hosts\": [\n    \"a\",\n    \"b\"\n  ],\n  \"name\": \"base\"\n}",
+		},
+		{
+			Args: []string{`--schema`, `schema.yaml`, `--set`, `db.port=0`, `--set`, `db.user=u`, `--set`, `hosts[2]=C`, `--unset`, `name`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Expected: `schema: missing required property "name"
+schema: db.port: 0 is less than the minimum 1 (set by --set db.port)
+schema: db.user: property "user" is not allowed (set by --set db.user)
+schema: hosts[2]: "C" doesn't match the pattern "^[a-z]+$" (set by --set hosts[2])
+invalid config: 4 schema violation(s)
+`,
+			Code: CodeSchemaViolation,
+		},
+		{
+			Args:     []string{`--schema`, `schema.yaml`, `conflict-base.yaml`, `conflict-overlay.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "schema: db: expected object, got string (set by conflict-overlay.yaml)\nschema: hosts: expected array, got object (set by conflict-overlay.yaml)\ninvalid config: 2 schema violation(s)\n",
+			Code:     CodeSchemaViolation,
+		},
+		{
+			Args: []string{`--schema`, `simple.json`, `-q`, `[`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`--schema`, `ops.json`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`--schema`, `missing.json`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeReadError,
+		},
+		{
+			Args:  []string{`schema`, `infer`, `-f`, `yaml`, `conflict-base.yaml`, `--json`, `-`},
+			Dir:   pkgPath + `/testdata`,
+			Stdin: `{"db": {"host": "y"}, "name": "base", "debug": true}`,
+			Expected: `$schema: https://json-schema.org/draft/2020-12/schema
+properties:
+  db:
+    properties:
+      host:
+        type: string
+      port:
+        type: integer
+    required:
+    - host
+    type: object
+  debug:
+    type: boolean
+  hosts:
+    items:
+      type: string
+    type: array
+  name:
+    enum:
+    - base
+    type: string
+required:
+- db
+- name
+type: object
+`,
+		},
+		{
+			Args: []string{`schema`, `infer`, `-f`, `env`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadFormat,
+		},
+		{
+			Args: []string{`schema`, `infer`},
+			Code: CodeNoTargets,
+		},
+		{
+			Args: []string{`codegen`, `go`, `--package`, `cfg`, `--type`, `Example`, `example.json`, `example.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Expected: "// Code generated by goconfigger codegen go. DO NOT EDIT.\n" + `
+package cfg
+
+type Example struct {
+	Array      []int         ` + "`json:\"array\" yaml:\"array\"`" + `
+	Nested     ExampleNested ` + "`json:\"nested\" yaml:\"nested\"`" + `
+	Unique     *bool         ` + "`json:\"unique,omitempty\" yaml:\"unique,omitempty\"`" + `
+	UniqueYaml *float64      ` + "`json:\"unique_yaml,omitempty\" yaml:\"unique_yaml,omitempty\"`" + `
+}
+
+type ExampleNested struct {
+	Another    map[string]interface{} ` + "`json:\"another,omitempty\" yaml:\"another,omitempty\"`" + `
+	More       []float64              ` + "`json:\"more,omitempty\" yaml:\"more,omitempty\"`" + `
+	Overridden interface{}            ` + "`json:\"overridden\" yaml:\"overridden\"`" + `
+}
+`,
+		},
+		{
+			Args: []string{`codegen`, `go`, `--type`, `a.b`, `example.json`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`get`},
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`get`, `db`},
+			Code: CodeNoTargets,
+		},
+		{
+			Args:     []string{`-f`, `json`, `--query`, `services[?@.enabled == true].image`, `--`, `--json`, `-`},
+			Stdin:    `{"services": [{"image": "a", "enabled": true}, {"image": "b", "enabled": false}, {"image": "c", "enabled": true}]}`,
+			Expected: "[\n  \"a\",\n  \"c\"\n]",
+		},
+		{
+			Args:     []string{`-q`, `$.db`, `conflict-base.yaml`},
+			Dir:      pkgPath + `/testdata`,
+			Expected: "host: x\nport: 5432\n",
+		},
+		{
+			Args: []string{`-q`, `hosts[*`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+		{
+			Args: []string{`-q`, `db`, `--explain`, `conflict-base.yaml`},
+			Dir:  pkgPath + `/testdata`,
+			Code: CodeBadArgument,
+		},
+	}
+
+	bin := buildBinary(t)
+	defer os.Remove(bin)
+
+	for _, testCase := range testCases {
 		cmd := exec.Command(bin, testCase.Args...)
+		cmd.Dir = testCase.Dir
+		if testCase.Env != nil {
+			cmd.Env = append(os.Environ(), testCase.Env...)
+		}
+		if testCase.Stdin != "" {
+			cmd.Stdin = strings.NewReader(testCase.Stdin)
+		}
 
 		output, err := cmd.CombinedOutput()
 		outputStr := string(output)
@@ -394,6 +1779,10 @@ unique_yaml: 14.64
 					}
 				}
 			}
+			// commands that fail to indicate a result, e.g. diff, may also check the output
+			if testCase.Expected != "" && testCase.Expected != outputStr {
+				t.Errorf("expected output != actual\nEXPECTED:\n%s\nACTUAL:\n%s", testCase.Expected, outputStr)
+			}
 			continue
 		}
 
@@ -408,3 +1797,241 @@ unique_yaml: 14.64
 		}
 	}
 }
+
+func TestInPlace(t *testing.T) {
+	bin := buildBinary(t)
+	defer os.Remove(bin)
+
+	dir, err := ioutil.TempDir(``, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original, err := ioutil.ReadFile(pkgPath + `/testdata/simple.yml`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, `simple.yml`)
+	if err := ioutil.WriteFile(target, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bin, `--in-place`, `--backup`, `.bak`, target, pkgPath+`/testdata/simple.json`)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, string(output))
+	} else if len(output) != 0 {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	if b, err := ioutil.ReadFile(target); err != nil {
+		t.Fatal(err)
+	} else if expected := "four: 34\nthree: 23\ntwo: 22\n"; string(b) != expected {
+		t.Errorf("expected output != actual\nEXPECTED:\n%s\nACTUAL:\n%s", expected, b)
+	}
+
+	if stats, err := os.Stat(target); err != nil {
+		t.Fatal(err)
+	} else if stats.Mode().Perm() != 0600 {
+		t.Errorf("unexpected mode %v", stats.Mode())
+	}
+
+	if b, err := ioutil.ReadFile(target + `.bak`); err != nil {
+		t.Fatal(err)
+	} else if string(b) != string(original) {
+		t.Errorf("unexpected backup: %s", b)
+	}
+
+	if files, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 2 {
+		t.Errorf("unexpected files: %v", files)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	bin := buildBinary(t)
+	defer os.Remove(bin)
+
+	dir, err := ioutil.TempDir(``, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, `generated.yaml`)
+	inputs := []string{pkgPath + `/testdata/simple.json`, pkgPath + `/testdata/simple.yml`}
+
+	cmd := exec.Command(bin, append([]string{`-o`, target}, inputs...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, string(output))
+	} else if len(output) != 0 {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	if b, err := ioutil.ReadFile(target); err != nil {
+		t.Fatal(err)
+	} else if expected := "four: 34\nthree: 33\ntwo: 22\n"; string(b) != expected {
+		t.Errorf("expected output != actual\nEXPECTED:\n%s\nACTUAL:\n%s", expected, b)
+	}
+
+	cmd = exec.Command(bin, append([]string{`--check`, `-o`, target}, inputs...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, string(output))
+	} else if len(output) != 0 {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	// the generated file is now out of date
+	cmd = exec.Command(bin, append([]string{`--check`, `-o`, target, `--set`, `two=2`}, inputs...)...)
+	output, err := cmd.CombinedOutput()
+	if err, ok := err.(*exec.ExitError); !ok || err.Sys().(syscall.WaitStatus).ExitStatus() != CodeCheckFailed {
+		t.Fatalf("expected error code %v got %v", CodeCheckFailed, err)
+	}
+	if expected := "--- " + target + "\n+++ " + target + " (expected)\n@@ -1,3 +1,3 @@\n four: 34\n three: 33\n-two: 22\n+two: 2\n"; string(output) != expected {
+		t.Errorf("expected output != actual\nEXPECTED:\n%s\nACTUAL:\n%s", expected, output)
+	}
+}
+
+func buildBinary(t *testing.T) string {
+	dir, err := ioutil.TempDir(``, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, `goconfigger.exe`)
+	cmd := exec.Command(`go`, `build`, `-v`, `-o`, bin, pkgPath)
+	cmd.Dir = pkgPath
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestSourceLines(t *testing.T) {
+	for _, testCase := range []struct {
+		Format   parser.Format
+		Data     string
+		Expected map[string]int
+	}{
+		{
+			Format: parser.JSON,
+			Data: `{
+  "a": 1,
+  "b": {
+    "c": [
+      true,
+      {"d": null}
+    ]
+  },
+  "e.f": "x"
+}`,
+			Expected: map[string]int{
+				``:         1,
+				`a`:        2,
+				`b`:        3,
+				`b.c`:      4,
+				`b.c[0]`:   5,
+				`b.c[1]`:   6,
+				`b.c[1].d`: 6,
+				`["e.f"]`:  9,
+			},
+		},
+		{
+			Format: parser.Env,
+			Data: `# comment
+A=1
+
+export B=2
+C: 3
+A=4
+`,
+			Expected: map[string]int{
+				`A`: 6,
+				`B`: 4,
+				`C`: 5,
+			},
+		},
+		{
+			Format: parser.YAML,
+			Data: `# comment
+base: &base
+  x: 1
+  y: [2, 3]
+a:
+  <<: *base
+  y:
+    - 4
+  z: *base
+"k.l": null
+---
+other: 1
+`,
+			Expected: map[string]int{
+				``:          2,
+				`base`:      2,
+				`base.x`:    3,
+				`base.y`:    4,
+				`base.y[0]`: 4,
+				`base.y[1]`: 4,
+				`a`:         5,
+				`a.x`:       3,
+				`a.y`:       7,
+				`a.y[0]`:    8,
+				`a.z`:       9,
+				`a.z.x`:     3,
+				`a.z.y`:     4,
+				`a.z.y[0]`:  4,
+				`a.z.y[1]`:  4,
+				`["k.l"]`:   10,
+			},
+		},
+		{
+			Format: parser.YAML,
+			Data:   `a: [`,
+		},
+		{
+			Format: parser.Auto,
+			Data:   `a: 1`,
+		},
+	} {
+		if diff := deep.Equal(testCase.Expected, sourceLines(testCase.Format, []byte(testCase.Data))); diff != nil {
+			t.Errorf("%v: %v", testCase.Format, diff)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, testCase := range []struct {
+		A, B     string
+		Expected string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", " a\n b\n c\n"},
+		{"", "a\n", "+a\n"},
+		{"a\n", "", "-a\n"},
+		{"a\n", "b\n", "-a\n+b\n"},
+		{"a\nb\nc\n", "a\nx\nc\nd\n", " a\n-b\n+x\n c\n+d\n"},
+		{"b\n", "a\nb\nc\n", "+a\n b\n+c\n"},
+		{"a\nb\nc\nd\n", "c\nd\na\nb\n", "-a\n-b\n c\n d\n+a\n+b\n"},
+	} {
+		var b strings.Builder
+		for _, op := range diffLines(testCase.A, testCase.B) {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Line)
+		}
+		if b.String() != testCase.Expected {
+			t.Errorf("%q to %q: unexpected diff:\n%s", testCase.A, testCase.B, b.String())
+		}
+	}
+
+	// large inputs must not require quadratic space
+	var x, y strings.Builder
+	for i := 0; i < 5000; i++ {
+		x.WriteString(strings.Repeat("x", i%10) + "\n")
+		y.WriteString(strings.Repeat("y", i%10) + "\n")
+	}
+	if ops := diffLines(x.String(), y.String()); len(ops) != 10000-500 {
+		t.Errorf("unexpected number of operations: %d", len(ops))
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	configger "github.com/joeycumines/go-configger"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pipeline reads and merges CONFIG arguments, as configured by the options shared by every command, see mergeFlags.
+type pipeline struct {
+	Formats map[string]parser.Format
+	Parser  parser.Config
+	Options merge.Options
+	// Env is the environment variable source, merged after every CONFIG, if set.
+	Env *configger.EnvOptions
+	// Overrides are applied in order, after the environment.
+	Overrides []override
+	Finder    fileFinder
+	// SkipUnknown skips files from directories and globs without a known extension, rather than failing.
+	SkipUnknown bool
+	// ConflictReport prints a warning to stderr for every conflict.
+	ConflictReport bool
+	// SourceLines tracks the line of each path, for Explain.
+	SourceLines bool
+}
+
+// patchFormats are the FORMAT flags marking a CONFIG as a JSON Patch, where Auto uses the file extension.
+var patchFormats = map[string]parser.Format{
+	"patch":      parser.Auto,
+	"json-patch": parser.JSON,
+	"yaml-patch": parser.YAML,
+	"yml-patch":  parser.YAML,
+}
+
+// newPipeline loads the options shared by every command, from c.
+func newPipeline(c *cli.Context) (*pipeline, error) {
+	p := &pipeline{
+		Formats:        AppFormats(),
+		Parser:         AppParser(),
+		ConflictReport: c.Bool("conflict-report"),
+	}
+
+	// handle the environment, as the final input
+	if c.IsSet("env-prefix") {
+		// an empty prefix would merge every variable, e.g. HOME and PATH, and any secrets
+		if c.String("env-prefix") == "" {
+			return nil, cli.NewExitError("invalid --env-prefix: must not be empty", CodeBadArgument)
+		}
+		p.Env = &configger.EnvOptions{
+			Prefix:    c.String("env-prefix"),
+			Separator: c.String("env-separator"),
+		}
+		if envCase, err := configger.ParseEnvCase(c.String("env-case")); err != nil {
+			return nil, cli.NewExitError(fmt.Sprintf("invalid --env-case: %s", err.Error()), CodeBadArgument)
+		} else {
+			p.Env.Case = envCase
+		}
+	}
+
+	// handle mode
+	p.Options = merge.Options{
+		Include:    c.StringSlice("whitelist"),
+		Exclude:    c.StringSlice("blacklist"),
+		MergePatch: c.Bool("merge-patch"),
+	}
+	if policy, err := merge.ParseConflictPolicy(c.String("conflict")); err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("invalid --conflict: %s", err.Error()), CodeBadArgument)
+	} else {
+		p.Options.Conflict = policy
+	}
+	for _, strategy := range c.StringSlice("array-strategy") {
+		rule, err := merge.ParseArrayRule(strategy)
+		if err != nil {
+			return nil, cli.NewExitError(fmt.Sprintf("invalid array strategy '%s': %s", strategy, err.Error()), CodeBadArgument)
+		}
+		p.Options.Arrays = append(p.Options.Arrays, rule)
+	}
+	if exclusion, err := merge.ParseArrayExclusion(c.String("array-exclusion")); err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("invalid --array-exclusion: %s", err.Error()), CodeBadArgument)
+	} else {
+		p.Options.ArrayExclusion = exclusion
+	}
+	if _, err := merge.New(p.Options); err != nil {
+		return nil, cli.NewExitError(err.Error(), CodeBadArgument)
+	}
+
+	// handle overrides, which are shared by each of the override options
+	if f, ok := c.Generic("set").(*overrideFlag); ok {
+		for _, o := range *f.List {
+			parsed, err := parseOverride(o)
+			if err != nil {
+				return nil, cli.NewExitError(fmt.Sprintf("invalid --%s '%s': %s", o.Flag, o.Raw, err.Error()), CodeBadArgument)
+			}
+			p.Overrides = append(p.Overrides, parsed)
+		}
+	}
+
+	// handle file discovery
+	p.Finder = fileFinder{
+		Recursive: c.Bool("recursive"),
+		Include:   c.StringSlice("file-include"),
+		Exclude:   c.StringSlice("file-exclude"),
+	}
+	switch unknown := c.String("unknown-ext"); unknown {
+	case "", "fail":
+	case "skip":
+		p.SkipUnknown = true
+	default:
+		return nil, cli.NewExitError("invalid --unknown-ext: "+unknown, CodeBadArgument)
+	}
+
+	return p, nil
+}
+
+// Inputs parses CONFIG arguments, reading each config, where everything following a "--" separator is a literal PATH.
+func (p *pipeline) Inputs(args []string) ([]mergeTarget, error) {
+	var (
+		inputList = make([]mergeTarget, 0)
+		literal   bool
+	)
+	for i := 0; i < len(args); i++ {
+		if !literal && args[i] == "--" {
+			literal = true
+			continue
+		}
+
+		var (
+			format parser.Format
+			flag   string
+			inc    = 1
+			ok     bool
+			index  = -1
+			patch  bool
+		)
+
+		// try to parse the format via a flag? e.g. --json file_path
+		if !literal && i < len(args)-1 {
+			if v := []rune(strings.ToLower(args[i])); len(v) > 2 && v[0] == '-' && v[1] == '-' {
+				flag = string(v[2:])
+				if format, ok = p.Formats[flag]; !ok {
+					format, ok = patchFormats[flag]
+					patch = ok
+				}
+			}
+		}
+		if ok {
+			// found a flag, does it require any extra args?
+			switch flag {
+			case `yaml-index`, `yml-index`:
+				if i >= len(args)-2 {
+					ok = false
+				} else if v, err := strconv.Atoi(args[i+1]); err != nil || v < 0 {
+					return nil, cli.NewExitError(fmt.Sprintf("invalid %s argument index: %s", args[i], args[i+1]), CodeBadArgument)
+				} else {
+					inc++
+					index = v
+				}
+			}
+		}
+		explicit := ok && format != parser.Auto
+		if ok {
+			// successfully consumed a format, we can increment
+			i += inc
+
+			// the format may also precede the separator, e.g. --json -- --file_path
+			if !literal && args[i] == "--" {
+				literal = true
+				i++
+				if i >= len(args) {
+					return nil, cli.NewExitError(fmt.Sprintf("missing PATH after %s", strings.Join(args[i-inc-1:], " ")), CodeBadArgument)
+				}
+			}
+		}
+		stdin := !literal && args[i] == "-"
+
+		// directories and glob patterns are expanded into many files, each with their own format, unless explicit
+		if !stdin {
+			files, err := p.Finder.Expand(args[i])
+			if err != nil {
+				return nil, cli.NewExitError(fmt.Sprintf("unable to read '%s': %s", args[i], err.Error()), CodeReadError)
+			}
+			if files != nil {
+				if index >= 0 {
+					return nil, cli.NewExitError(fmt.Sprintf("unable to select yaml at %d of '%s'", index, args[i]), CodeBadArgument)
+				}
+				for _, file := range files {
+					fileFormat := format
+					if !explicit {
+						if fileFormat, ok = formatFromPath(p.Formats, file); !ok {
+							if p.SkipUnknown {
+								continue
+							}
+							return nil, cli.NewExitError("unable to determine the format from: "+file, CodeBadFormat)
+						}
+					}
+					target, err := openTarget(fileFormat, file, index, false)
+					if err != nil {
+						return nil, err
+					}
+					target.Patch = patch
+					target.Expanded = true
+					inputList = append(inputList, target)
+				}
+				continue
+			}
+		}
+
+		if !explicit {
+			// parse the format via the file path?
+			if format, ok = formatFromPath(p.Formats, args[i]); !ok {
+				return nil, cli.NewExitError("unable to determine the format from: "+args[i], CodeBadFormat)
+			}
+		}
+
+		target, err := openTarget(format, args[i], index, stdin)
+		if err != nil {
+			return nil, err
+		}
+		target.Patch = patch
+		inputList = append(inputList, target)
+	}
+
+	if len(inputList) <= 0 {
+		return nil, cli.NewExitError("at least one target config must be provided", CodeNoTargets)
+	}
+
+	return inputList, nil
+}
+
+// Merge merges inputs, then the environment, and any overrides, returning the result, and the mode, which holds the
+// provenance and conflicts.
+func (p *pipeline) Merge(inputList []mergeTarget) (interface{}, *merge.Mode, error) {
+	mode, err := merge.New(p.Options)
+	if err != nil {
+		return nil, nil, cli.NewExitError(err.Error(), CodeBadArgument)
+	}
+
+	var data interface{}
+	for i, input := range inputList {
+		// read the file, finding the line of each path, if required
+		reader := input.Reader
+		mode.SourceLines = nil
+		if p.SourceLines {
+			b, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return nil, nil, cli.NewExitError(fmt.Sprintf("unable to read '%s': %s", input.Name(), err.Error()), CodeReadError)
+			}
+			// a single yaml document has been re-encoded, so the lines wouldn't match the file
+			if input.Index < 0 {
+				mode.SourceLines = sourceLines(input.Format, b)
+			}
+			reader = bytes.NewReader(b)
+		}
+		newData, err := p.Parser.Read(input.Format, reader)
+		if err != nil {
+			return nil, nil, cli.NewExitError(fmt.Sprintf("unable to parse file format %v: %s", input.Format, err.Error()), CodeReadError)
+		}
+		mode.Source = input.Name()
+		switch {
+		case input.Patch:
+			if data, err = mode.Patch(data, newData); err != nil {
+				return nil, nil, cli.NewExitError(fmt.Sprintf("unable to apply '%s': %s", input.Path, err.Error()), CodePatchError)
+			}
+		case i == 0:
+			data = mode.Filter(newData)
+		default:
+			if data, err = mode.Merge(data, newData); err != nil {
+				return nil, nil, cli.NewExitError(fmt.Sprintf("unable to merge '%s': %s", input.Path, err.Error()), CodeConflict)
+			}
+		}
+	}
+
+	if p.Env != nil {
+		mode.SourceLines = nil
+		mode.Source = "env"
+		if data, err = mode.Merge(data, configger.EnvValue(os.Environ(), *p.Env, data)); err != nil {
+			return nil, nil, cli.NewExitError(fmt.Sprintf("unable to merge the environment: %s", err.Error()), CodeConflict)
+		}
+	}
+
+	for _, o := range p.Overrides {
+		mode.SourceLines = nil
+		if data, err = applyOverride(mode, data, o); err != nil {
+			return nil, nil, cli.NewExitError(fmt.Sprintf("unable to apply --%s '%s': %s", o.Flag, o.Raw, err.Error()), CodeBadArgument)
+		}
+	}
+
+	if p.ConflictReport {
+		for _, conflict := range mode.Conflicts {
+			fmt.Fprintln(os.Stderr, "warning: "+conflict.Warning())
+		}
+	}
+
+	return data, mode, nil
+}
+
+// Examples merges each input separately, as an example, with the environment, and any overrides, returning the
+// results, in order.
+func (p *pipeline) Examples(inputList []mergeTarget) ([]interface{}, error) {
+	examples := make([]interface{}, 0, len(inputList))
+	for _, input := range inputList {
+		data, _, err := p.Merge([]mergeTarget{input})
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, data)
+	}
+	return examples, nil
+}
+
+// defaultFormat is the format of the first input that isn't a patch, or the first input, if they are all patches.
+func defaultFormat(inputList []mergeTarget) parser.Format {
+	for _, input := range inputList {
+		if !input.Patch {
+			return input.Format
+		}
+	}
+	return inputList[0].Format
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"github.com/joeycumines/go-configger/schema"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strings"
+)
+
+const schemaInferUsageText = `goconfigger schema infer [OPTIONS] [--] CONFIG [CONFIG...]
+    prints a JSON Schema describing every CONFIG, each of which is an example,
+    rather than being merged, where properties are required if they exist in
+    every example
+    CONFIG: see goconfigger help, where each option applies to every CONFIG`
+
+func schemaCommand() cli.Command {
+	return cli.Command{
+		Name:  "schema",
+		Usage: "work with JSON Schemas, see --schema for validation",
+		Subcommands: []cli.Command{
+			{
+				Name:           "infer",
+				Usage:          "print a JSON Schema inferred from example CONFIGs",
+				UsageText:      schemaInferUsageText,
+				SkipArgReorder: true,
+				Flags: append(
+					[]cli.Flag{
+						cli.StringFlag{
+							Name:  "format,f",
+							Value: "json",
+							Usage: "target format for the schema, one of (json, yaml, yml)",
+						},
+						cli.IntFlag{
+							Name:  "max-enum",
+							Value: 5,
+							Usage: "the maximum number of distinct values of a string for an enum, where at least one value must be repeated, or 0 to disable enums",
+						},
+					},
+					mergeFlags()...,
+				),
+				Action: schemaInferAction,
+			},
+		},
+	}
+}
+
+func schemaInferAction(c *cli.Context) error {
+	targetFormat, ok := AppFormats()[strings.ToLower(c.String("format"))]
+	if !ok || (targetFormat != parser.JSON && targetFormat != parser.YAML) {
+		return cli.NewExitError("invalid --format: "+c.String("format"), CodeBadFormat)
+	}
+	if c.Int("max-enum") < 0 {
+		return cli.NewExitError("invalid --max-enum: must not be negative", CodeBadArgument)
+	}
+
+	p, err := newPipeline(c)
+	if err != nil {
+		return err
+	}
+	inputList, err := p.Inputs(c.Args())
+	if err != nil {
+		return err
+	}
+
+	examples, err := p.Examples(inputList)
+	if err != nil {
+		return err
+	}
+
+	result := schema.Infer(schema.InferOptions{MaxEnum: c.Int("max-enum")}, examples...)
+
+	buffer := bytes.NewBufferString("")
+	if err := p.Parser.Write(targetFormat, result, buffer); err != nil {
+		return cli.NewExitError(fmt.Sprintf("unable to output to format %v: %s", targetFormat, err.Error()), CodeWriteError)
+	}
+	fmt.Fprint(os.Stdout, buffer.String())
+
+	return nil
+}
+
+// loadSchema reads and compiles the JSON Schema at p, in any supported format, determined by the file extension.
+func loadSchema(appParser parser.Config, appFormats map[string]parser.Format, p string) (*schema.Schema, error) {
+	format, ok := formatFromPath(appFormats, p)
+	if !ok {
+		return nil, cli.NewExitError("unable to determine the format from: "+p, CodeBadFormat)
+	}
+	target, err := openTarget(format, p, -1, false)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := appParser.Read(format, target.Reader)
+	if err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("unable to parse schema '%s': %s", p, err.Error()), CodeReadError)
+	}
+	s, err := schema.Compile(doc)
+	if err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("unable to load schema '%s': %s", p, err.Error()), CodeBadArgument)
+	}
+	return s, nil
+}
+
+// validate validates data against s, printing each violation to stderr, with the CONFIGs that set the invalid value,
+// if known.
+func validate(s *schema.Schema, mode *merge.Mode, data interface{}) error {
+	violations := s.Validate(data)
+	if len(violations) == 0 {
+		return nil
+	}
+	for _, v := range violations {
+		message := "schema: " + v.Error()
+		// a missing required property wasn't set by anything
+		if origins := mode.Origins(data, v.Path); len(origins) != 0 && v.Keyword != "required" {
+			sources := make([]string, 0, len(origins))
+			for _, origin := range origins {
+				sources = append(sources, origin.String())
+			}
+			message += " (set by " + strings.Join(sources, ", ") + ")"
+		}
+		fmt.Fprintln(os.Stderr, message)
+	}
+	return cli.NewExitError(fmt.Sprintf("invalid config: %d schema violation(s)", len(violations)), CodeSchemaViolation)
+}
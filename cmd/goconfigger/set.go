@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"strings"
+)
+
+// override is a single --set, --set-string, --set-json or --unset option, see parseOverride.
+type override struct {
+	Flag string
+	// Raw is the value of the option, as provided
+	Raw   string
+	Path  merge.Path
+	Value interface{}
+}
+
+// overrideFlag implements flag.Value for each of the override options, appending to a shared list, so that they may
+// be applied in the order they were given.
+type overrideFlag struct {
+	Name string
+	List *[]override
+}
+
+func (f *overrideFlag) Set(s string) error {
+	*f.List = append(*f.List, override{Flag: f.Name, Raw: s})
+	return nil
+}
+
+func (f *overrideFlag) String() string { return "" }
+
+// parseOverride parses the Raw value of an override option, PATH=VALUE, or just PATH, for unset.
+func parseOverride(o override) (override, error) {
+	var (
+		result = o
+		s      = o.Raw
+		p      = s
+	)
+	if o.Flag != "unset" {
+		i := pathLength(s)
+		if i == len(s) || s[i] != '=' {
+			return override{}, fmt.Errorf("expected PATH=VALUE")
+		}
+		p, s = s[:i], s[i+1:]
+	}
+
+	path, err := merge.ParsePath(p)
+	if err != nil {
+		return override{}, err
+	}
+	if !path.Literal() {
+		return override{}, fmt.Errorf("invalid path '%s': wildcards aren't supported", p)
+	}
+	result.Path = path
+
+	switch o.Flag {
+	case "set":
+		result.Value, err = parseScalar(s)
+	case "set-string":
+		result.Value = s
+	case "set-json":
+		result.Value, err = parser.JSONRead(strings.NewReader(s))
+	}
+	if err != nil {
+		return override{}, err
+	}
+
+	return result, nil
+}
+
+// pathLength finds the length of the path at the start of s, up to the first '=' that isn't escaped, or within a
+// subscript, e.g. `a["b=c"]=d` is 8.
+func pathLength(s string) int {
+	var quote rune
+	subscript := false
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case subscript && (c == '"' || c == '\''):
+			quote = c
+		case c == '[':
+			subscript = true
+		case c == ']':
+			subscript = false
+		case c == '=' && !subscript:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// parseScalar parses s as a yaml scalar, or returns it as a string, if it is empty, or not a scalar.
+func parseScalar(s string) (interface{}, error) {
+	if strings.TrimSpace(s) == "" {
+		return s, nil
+	}
+	v, err := parser.YAMLRead(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return s, nil
+	}
+	return v, nil
+}
+
+// applyOverride sets or unsets the value of o, within data.
+func applyOverride(mode *merge.Mode, data interface{}, o override) (interface{}, error) {
+	mode.Source = fmt.Sprintf("--%s %s", o.Flag, o.Path)
+	if o.Flag == "unset" {
+		return mode.Unset(data, o.Path)
+	}
+	return mode.Set(data, o.Path, o.Value)
+}
@@ -0,0 +1,244 @@
+// Package codegen generates source code from values like those returned by parser.Reader, as used by the goconfigger
+// command.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/joeycumines/go-configger/schema"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	// GoOptions configures Go.
+	GoOptions struct {
+		// Package is the name of the package, which defaults to "config".
+		Package string
+		// Type is the name of the root type, which defaults to "Config".
+		Type string
+	}
+
+	goGenerator struct {
+		// defs are the generated type definitions, in order
+		defs  []string
+		names map[string]bool
+	}
+)
+
+// initialisms are the words formatted in upper case, within identifiers, per the Go conventions.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DB": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true,
+	"TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true,
+	"URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// Go generates Go type definitions, with json and yaml tags, describing every example, where objects are structs,
+// unified across every example and array element, see schema.Infer. Properties that don't exist in every object at
+// their path, or that may be null, are pointers (unless they are already nillable), and values of more than one type
+// are interface{}.
+func Go(options GoOptions, examples ...interface{}) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = "config"
+	}
+	if options.Type == "" {
+		options.Type = "Config"
+	}
+	for _, name := range []string{options.Package, options.Type} {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("invalid identifier %q", name)
+		}
+	}
+
+	g := &goGenerator{names: make(map[string]bool)}
+	root := schema.Infer(schema.InferOptions{}, examples...)
+	if isStruct(root) {
+		g.define(options.Type, root)
+	} else {
+		// the root is defined first, before any structs within it
+		g.names[options.Type] = true
+		g.defs = append(g.defs, "")
+		// typeExpr may append to (and reallocate) defs, so it must be called before the assignment
+		expr := g.typeExpr(options.Type+"Item", root)
+		g.defs[0] = "type " + options.Type + " " + expr
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by goconfigger codegen go. DO NOT EDIT.\n\npackage %s\n", options.Package)
+	for _, def := range g.defs {
+		b.WriteString("\n" + def + "\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+// isStruct returns true if s is an object (or null) with properties, which is generated as a struct.
+func isStruct(s map[string]interface{}) bool {
+	properties, ok := s["properties"].(map[string]interface{})
+	types := nonNullTypes(s)
+	return ok && len(properties) != 0 && len(types) == 1 && types[0] == "object"
+}
+
+// nonNullTypes returns the types of s, other than null.
+func nonNullTypes(s map[string]interface{}) []string {
+	var types []string
+	switch t := s["type"].(type) {
+	case string:
+		if t != "null" {
+			types = append(types, t)
+		}
+	case []interface{}:
+		for _, t := range t {
+			if t != "null" {
+				types = append(types, t.(string))
+			}
+		}
+	}
+	return types
+}
+
+// define adds the definition of a struct type for s (see isStruct), followed by any nested structs, named after their
+// parent and field, returning the (unique) name of the type.
+func (g *goGenerator) define(name string, s map[string]interface{}) string {
+	name = uniqueName(g.names, name)
+	i := len(g.defs)
+	g.defs = append(g.defs, "")
+	properties := s["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := s["required"].([]interface{}); ok {
+		for _, k := range list {
+			required[k.(string)] = true
+		}
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	fields := make(map[string]bool)
+	for _, k := range keys {
+		field := uniqueName(fields, exportedName(k))
+		p, _ := properties[k].(map[string]interface{})
+		expr := g.typeExpr(name+field, p)
+		if (!required[k] || nullable(p)) && !strings.HasPrefix(expr, "[]") && !strings.HasPrefix(expr, "map[") && expr != "interface{}" {
+			expr = "*" + expr
+		}
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%s yaml:%s`\n", field, expr, strconv.Quote(tag), strconv.Quote(tag))
+	}
+	b.WriteString("}")
+	g.defs[i] = "type " + name + " " + b.String()
+	return name
+}
+
+// typeExpr returns the type of s, where name is the name of any struct, which will be defined.
+func (g *goGenerator) typeExpr(name string, s map[string]interface{}) string {
+	types := nonNullTypes(s)
+	if len(types) != 1 {
+		return "interface{}"
+	}
+
+	switch types[0] {
+	case "boolean":
+		return "bool"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "string":
+		return "string"
+	case "array":
+		items, ok := s["items"].(map[string]interface{})
+		if !ok {
+			return "[]interface{}"
+		}
+		return "[]" + g.typeExpr(name, items)
+	case "object":
+		if !isStruct(s) {
+			return "map[string]interface{}"
+		}
+		return g.define(name, s)
+	}
+	return "interface{}"
+}
+
+// nullable returns true if s allows null, and another type.
+func nullable(s map[string]interface{}) bool {
+	if list, ok := s["type"].([]interface{}); ok {
+		for _, t := range list {
+			if t == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exportedName converts a key to an exported Go identifier, e.g. log_level to LogLevel, and db_url to DBURL.
+func exportedName(key string) string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) != 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	r := []rune(key)
+	for i, c := range r {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			flush()
+		// a new word starts at an upper case letter, following a lower case letter or digit, e.g. emptyDir
+		case unicode.IsUpper(c) && i > 0 && (unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1])):
+			flush()
+			word = append(word, c)
+		// or the last upper case letter of an initialism, that precedes a lower case letter, e.g. HTTPServer
+		case unicode.IsUpper(c) && i > 0 && unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1]):
+			flush()
+			word = append(word, c)
+		default:
+			word = append(word, c)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// uniqueName returns name, or name with the lowest numeric suffix that isn't in names, adding it to names.
+func uniqueName(names map[string]bool, name string) string {
+	unique := name
+	for i := 2; names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	names[unique] = true
+	return unique
+}
@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parseJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestGo(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Options  GoOptions
+		Examples []string
+		Expected string
+	}{
+		{
+			Name:    `unified`,
+			Options: GoOptions{Package: `cfg`},
+			Examples: []string{
+				`{"name":"a","db":{"url":"x","pool_size":1},"ratio":1,"tags":["a"],"services":[{"http-port":80,"enabled":true},{"http-port":81,"extra":{}}]}`,
+				`{"name":"b","db":{"url":"y","pool_size":2,"timeout":null},"ratio":0.5,"debug":false,"mixed":[1,"a"],"note":null}`,
+			},
+			Expected: "// Code generated by goconfigger codegen go. DO NOT EDIT.\n" + `
+package cfg
+
+type Config struct {
+	DB       ConfigDB         ` + "`json:\"db\" yaml:\"db\"`" + `
+	Debug    *bool            ` + "`json:\"debug,omitempty\" yaml:\"debug,omitempty\"`" + `
+	Mixed    []interface{}    ` + "`json:\"mixed,omitempty\" yaml:\"mixed,omitempty\"`" + `
+	Name     string           ` + "`json:\"name\" yaml:\"name\"`" + `
+	Note     interface{}      ` + "`json:\"note,omitempty\" yaml:\"note,omitempty\"`" + `
+	Ratio    float64          ` + "`json:\"ratio\" yaml:\"ratio\"`" + `
+	Services []ConfigServices ` + "`json:\"services,omitempty\" yaml:\"services,omitempty\"`" + `
+	Tags     []string         ` + "`json:\"tags,omitempty\" yaml:\"tags,omitempty\"`" + `
+}
+
+type ConfigDB struct {
+	PoolSize int         ` + "`json:\"pool_size\" yaml:\"pool_size\"`" + `
+	Timeout  interface{} ` + "`json:\"timeout,omitempty\" yaml:\"timeout,omitempty\"`" + `
+	URL      string      ` + "`json:\"url\" yaml:\"url\"`" + `
+}
+
+type ConfigServices struct {
+	Enabled  *bool                  ` + "`json:\"enabled,omitempty\" yaml:\"enabled,omitempty\"`" + `
+	Extra    map[string]interface{} ` + "`json:\"extra,omitempty\" yaml:\"extra,omitempty\"`" + `
+	HTTPPort int                    ` + "`json:\"http-port\" yaml:\"http-port\"`" + `
+}
+`,
+		},
+		{
+			Name:     `nullable and collisions`,
+			Options:  GoOptions{Type: `Settings`},
+			Examples: []string{`{"a":{"b":1},"log_level":"x","logLevel":null}`, `{"a":null,"log_level":"y","logLevel":"z","1st":true}`},
+			Expected: "// Code generated by goconfigger codegen go. DO NOT EDIT.\n" + `
+package config
+
+type Settings struct {
+	X1st      *bool      ` + "`json:\"1st,omitempty\" yaml:\"1st,omitempty\"`" + `
+	A         *SettingsA ` + "`json:\"a\" yaml:\"a\"`" + `
+	LogLevel  *string    ` + "`json:\"logLevel\" yaml:\"logLevel\"`" + `
+	LogLevel2 string     ` + "`json:\"log_level\" yaml:\"log_level\"`" + `
+}
+
+type SettingsA struct {
+	B int ` + "`json:\"b\" yaml:\"b\"`" + `
+}
+`,
+		},
+		{
+			Name:     `array root`,
+			Examples: []string{`[{"a":1}]`},
+			Expected: "// Code generated by goconfigger codegen go. DO NOT EDIT.\n" + `
+package config
+
+type Config []ConfigItem
+
+type ConfigItem struct {
+	A int ` + "`json:\"a\" yaml:\"a\"`" + `
+}
+`,
+		},
+	} {
+		var examples []interface{}
+		for _, example := range testCase.Examples {
+			examples = append(examples, parseJSON(t, example))
+		}
+		b, err := Go(testCase.Options, examples...)
+		if err != nil {
+			t.Errorf("%s: %v", testCase.Name, err)
+		} else if string(b) != testCase.Expected {
+			t.Errorf("%s: expected output != actual\nEXPECTED:\n%s\nACTUAL:\n%s", testCase.Name, testCase.Expected, b)
+		}
+	}
+
+	if _, err := Go(GoOptions{Package: `a-b`}); err == nil || err.Error() != `invalid identifier "a-b"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	for key, expected := range map[string]string{
+		`name`:       `Name`,
+		`log_level`:  `LogLevel`,
+		`emptyDir`:   `EmptyDir`,
+		`db_url`:     `DBURL`,
+		`HTTPServer`: `HTTPServer`,
+		`api-key.id`: `APIKeyID`,
+		`x509`:       `X509`,
+		`2fa`:        `X2fa`,
+		`--`:         `X`,
+		`über`:       `Über`,
+		`SCREAMING`:  `SCREAMING`,
+		`userIDs`:    `UserIDs`,
+	} {
+		if actual := exportedName(key); actual != expected {
+			t.Errorf("%s: expected %s got %s", key, expected, actual)
+		}
+	}
+}
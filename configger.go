@@ -0,0 +1,179 @@
+// Package configger loads configuration from any number of sources (config files, the environment, etc), merging
+// them in order, using the same engine as the goconfigger command, and decoding the result into a Go value.
+//
+// Example:
+//
+//	var cfg struct {
+//		Host string `configger:"host,required"`
+//		Port int    `configger:"port" default:"8080"`
+//	}
+//	err := configger.Load(ctx, &cfg, configger.File("base.yaml"), configger.File("prod.env"), configger.Env("APP_"))
+package configger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+type (
+	// Source is a single input to Load, which is merged over the result of any prior sources.
+	Source interface {
+		// Name identifies the source, e.g. in errors, and the provenance of values.
+		Name() string
+		// Read loads the source into memory, as a value like those returned by parser.Reader, where base is the result
+		// of merging any prior sources (nil for the first).
+		Read(ctx context.Context, base interface{}) (interface{}, error)
+	}
+
+	// Loader implements Load, with configurable merge behavior and formats.
+	Loader struct {
+		// Options configures how sources are merged, the zero value behaves like the goconfigger command.
+		Options merge.Options
+		// Parser is used to read each source, defaulting to parser.Default.
+		Parser parser.Config
+	}
+
+	fileSource struct {
+		path   string
+		format parser.Format
+	}
+
+	readerSource struct {
+		name   string
+		format parser.Format
+		reader io.Reader
+	}
+
+	envSource struct {
+		options EnvOptions
+	}
+
+	parserKey struct{}
+)
+
+// Formats maps file extensions (lower case, without the dot) to formats, and is used by File.
+var Formats = map[string]parser.Format{
+	"env":        parser.Env,
+	"json":       parser.JSON,
+	"yaml":       parser.YAML,
+	"yml":        parser.YAML,
+	"env-simple": parser.EnvSimple,
+}
+
+// Load reads and merges sources, in order, decoding the result into v, which must be a non-nil pointer, see Decode.
+func Load(ctx context.Context, v interface{}, sources ...Source) error {
+	return Loader{}.Load(ctx, v, sources...)
+}
+
+// Load reads and merges sources, in order, decoding the result into v, which must be a non-nil pointer, see Decode.
+func (l Loader) Load(ctx context.Context, v interface{}, sources ...Source) error {
+	data, err := l.Merge(ctx, sources...)
+	if err != nil {
+		return err
+	}
+	return Decode(data, v)
+}
+
+// Merge reads and merges sources, in order, returning the result.
+func (l Loader) Merge(ctx context.Context, sources ...Source) (interface{}, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config := l.Parser
+	if config == nil {
+		config = parser.Default
+	}
+	ctx = context.WithValue(ctx, parserKey{}, config)
+	mode, err := merge.New(l.Options)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	for i, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := source.Read(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s': %s", source.Name(), err.Error())
+		}
+		mode.Source = source.Name()
+		if i == 0 {
+			data = mode.Filter(value)
+		} else if data, err = mode.Merge(data, value); err != nil {
+			return nil, fmt.Errorf("unable to merge '%s': %s", source.Name(), err.Error())
+		}
+	}
+	return data, nil
+}
+
+// File is a config file, the format of which is determined by the file extension, see Formats.
+func File(p string) Source {
+	format, ok := Formats[strings.ToLower(strings.TrimPrefix(path.Ext(p), "."))]
+	if !ok {
+		format = parser.Auto
+	}
+	return FileFormat(p, format)
+}
+
+// FileFormat is a config file in the given format.
+func FileFormat(p string, format parser.Format) Source {
+	return fileSource{path: p, format: format}
+}
+
+// Reader is a config, in the given format, read from r, where name identifies it.
+func Reader(name string, format parser.Format, r io.Reader) Source {
+	return readerSource{name: name, format: format, reader: r}
+}
+
+// Env is the environment variables starting with prefix, with the prefix removed, and names folded to lower case,
+// where a double underscore separates nested keys, e.g. APP_DB__HOST is db.host, for the prefix APP_, see EnvWith.
+// Note that an empty prefix selects every variable, e.g. HOME and PATH.
+func Env(prefix string) Source {
+	return EnvWith(EnvOptions{Prefix: prefix})
+}
+
+// EnvWith is the environment variables, converted using options, see EnvValue.
+func EnvWith(options EnvOptions) Source {
+	return envSource{options: options}
+}
+
+func (s fileSource) Name() string { return s.path }
+
+func (s fileSource) Read(ctx context.Context, base interface{}) (interface{}, error) {
+	if s.format == parser.Auto {
+		return nil, fmt.Errorf("unable to determine the format from the extension")
+	}
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	return contextParser(ctx).Read(s.format, bytes.NewReader(b))
+}
+
+func (s readerSource) Name() string { return s.name }
+
+func (s readerSource) Read(ctx context.Context, base interface{}) (interface{}, error) {
+	return contextParser(ctx).Read(s.format, s.reader)
+}
+
+func (s envSource) Name() string { return "env:" + s.options.Prefix }
+
+func (s envSource) Read(ctx context.Context, base interface{}) (interface{}, error) {
+	return EnvValue(os.Environ(), s.options, base), nil
+}
+
+func contextParser(ctx context.Context) parser.Config {
+	if config, ok := ctx.Value(parserKey{}).(parser.Config); ok {
+		return config
+	}
+	return parser.Default
+}
@@ -0,0 +1,154 @@
+package configger
+
+import (
+	"context"
+	"github.com/go-test/deep"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name  string `configger:"name,required"`
+	Debug bool   `configger:"debug"`
+	Level string `configger:"level" default:"info"`
+	DB    struct {
+		Host    string        `configger:"host"`
+		Port    int           `configger:"port"`
+		Timeout time.Duration `configger:"timeout"`
+	} `configger:"db"`
+	Servers []struct {
+		Name   string  `configger:"name"`
+		Weight float64 `configger:"weight"`
+	} `configger:"servers"`
+}
+
+func TestLoad(t *testing.T) {
+	setenv(t, map[string]string{
+		`APP_DB__HOST`: `db.internal`,
+		`APP_DB__PORT`: `6543`,
+		`APP_LEVEL`:    `debug`,
+		`OTHER_LEVEL`:  `error`,
+	})
+	var cfg testConfig
+	if err := Load(context.Background(), &cfg, File(`testdata/base.yaml`), File(`testdata/prod.env`), Env(`APP_`)); err != nil {
+		t.Fatal(err)
+	}
+	var expected testConfig
+	expected.Name = `production`
+	expected.Debug = true
+	expected.Level = `debug`
+	expected.DB.Host = `db.internal`
+	expected.DB.Port = 6543
+	expected.DB.Timeout = 5 * time.Second
+	expected.Servers = append(expected.Servers, struct {
+		Name   string  `configger:"name"`
+		Weight float64 `configger:"weight"`
+	}{`a`, 1}, struct {
+		Name   string  `configger:"name"`
+		Weight float64 `configger:"weight"`
+	}{`b`, 2})
+	if diff := deep.Equal(expected, cfg); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestLoader_Merge(t *testing.T) {
+	loader := Loader{Options: merge.Options{
+		Exclude: []string{`db.timeout`},
+		Arrays:  []merge.ArrayRule{{Path: `servers`, Strategy: merge.ArrayMergeByKey, Key: `name`}},
+	}}
+	data, err := loader.Merge(
+		context.Background(),
+		File(`testdata/base.yaml`),
+		Reader(`overlay`, parser.JSON, strings.NewReader(`{"servers": [{"name": "b", "weight": 3}, {"name": "c"}]}`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		`name`: `example`,
+		`db`:   map[string]interface{}{`host`: `localhost`, `port`: float64(5432)},
+		`servers`: []interface{}{
+			map[string]interface{}{`name`: `a`, `weight`: float64(1)},
+			map[string]interface{}{`name`: `b`, `weight`: float64(3)},
+			map[string]interface{}{`name`: `c`},
+		},
+	}
+	if diff := deep.Equal(expected, data); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestLoad_errors(t *testing.T) {
+	for _, testCase := range []struct {
+		Name    string
+		Sources []Source
+		Error   string
+	}{
+		{
+			Name:    `missing file`,
+			Sources: []Source{File(`testdata/missing.json`)},
+			Error:   `unable to read 'testdata/missing.json': open testdata/missing.json: no such file or directory`,
+		},
+		{
+			Name:    `unknown extension`,
+			Sources: []Source{File(`testdata/base.txt`)},
+			Error:   `unable to read 'testdata/base.txt': unable to determine the format from the extension`,
+		},
+		{
+			Name:    `missing required`,
+			Sources: []Source{Reader(`a`, parser.JSON, strings.NewReader(`{"debug": true}`))},
+			Error:   `name: missing required value`,
+		},
+		{
+			Name:    `invalid value`,
+			Sources: []Source{Reader(`a`, parser.JSON, strings.NewReader(`{"name": "a", "db": {"port": "x"}}`))},
+			Error:   `db.port: unable to decode string "x" into int: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			var cfg testConfig
+			err := Load(context.Background(), &cfg, testCase.Sources...)
+			if err == nil || err.Error() != testCase.Error {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Load(ctx, &testConfig{}, File(`testdata/base.yaml`)); err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func parseJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := parser.JSONRead(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,340 @@
+package configger
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeError indicates that the value at a path couldn't be decoded.
+type DecodeError struct {
+	Path merge.Path
+	Err  error
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Decode stores data, a value like those returned by parser.Reader, in the value pointed to by v.
+//
+// Struct fields are decoded from the key given by the `configger` tag (e.g. `configger:"name"`), defaulting to the
+// field name, where keys that don't match exactly are matched case insensitively (using the first match, in sorted
+// order), and a tag of "-" skips the field.
+// The "required" tag option (e.g. `configger:"name,required"`) makes a missing (or null) key an error, and a
+// `default` tag (e.g. `default:"8080"`) provides a value for a missing (or null) key, which is decoded like a string.
+// Embedded structs without a tag are decoded as if their fields were part of the outer struct.
+//
+// Strings will be converted to booleans, numbers and durations (see time.ParseDuration), as necessary, to support
+// formats like env, and types implementing encoding.TextUnmarshaler are decoded from strings.
+func Decode(data interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("decode requires a non-nil pointer, got %T", v)
+	}
+	return decodeValue(data, rv.Elem(), make(merge.Path, 0))
+}
+
+func (e *DecodeError) Error() string {
+	path := e.Path.String()
+	if path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", path, e.Err.Error())
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+func decodeValue(data interface{}, rv reflect.Value, path merge.Path) error {
+	if rv.Kind() == reflect.Ptr {
+		if data == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(data, rv.Elem(), path)
+	}
+
+	if s, ok := data.(string); ok && rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return &DecodeError{Path: copyPath(path), Err: err}
+		}
+		return nil
+	}
+
+	if rv.Type() == durationType {
+		if data == nil {
+			return nil
+		}
+		d, err := decodeDuration(data)
+		if err != nil {
+			return decodeError(data, rv, path, err)
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if data == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		value := reflect.ValueOf(data)
+		if !value.Type().AssignableTo(rv.Type()) {
+			return decodeError(data, rv, path, nil)
+		}
+		rv.Set(value)
+		return nil
+
+	case reflect.Struct:
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return decodeError(data, rv, path, nil)
+		}
+		return decodeStruct(m, rv, path)
+
+	case reflect.Map:
+		if data == nil {
+			return nil
+		}
+		m, ok := data.(map[string]interface{})
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return decodeError(data, rv, path, nil)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m)))
+		}
+		for k, v := range m {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(v, elem, append(path, merge.Segment{Value: k})); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if data == nil {
+			return nil
+		}
+		list, ok := data.([]interface{})
+		if !ok {
+			return decodeError(data, rv, path, nil)
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(list), len(list)))
+		} else if len(list) > rv.Len() {
+			return decodeError(data, rv, path, fmt.Errorf("too many elements (%d)", len(list)))
+		}
+		for i, v := range list {
+			if err := decodeValue(v, rv.Index(i), append(path, merge.Segment{Value: strconv.Itoa(i), Index: true})); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		switch t := data.(type) {
+		case string:
+			rv.SetString(t)
+		case float64:
+			rv.SetString(strconv.FormatFloat(t, 'f', -1, 64))
+		case bool:
+			rv.SetString(strconv.FormatBool(t))
+		default:
+			return decodeError(data, rv, path, nil)
+		}
+
+	case reflect.Bool:
+		switch t := data.(type) {
+		case bool:
+			rv.SetBool(t)
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(t))
+			if err != nil {
+				return decodeError(data, rv, path, nil)
+			}
+			rv.SetBool(b)
+		default:
+			return decodeError(data, rv, path, nil)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		f, err := decodeFloat(data)
+		if err != nil || rv.OverflowFloat(f) {
+			return decodeError(data, rv, path, err)
+		}
+		rv.SetFloat(f)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := decodeFloat(data)
+		if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || rv.OverflowInt(int64(f)) {
+			return decodeError(data, rv, path, err)
+		}
+		rv.SetInt(int64(f))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, err := decodeFloat(data)
+		if err != nil || f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || rv.OverflowUint(uint64(f)) {
+			return decodeError(data, rv, path, err)
+		}
+		rv.SetUint(uint64(f))
+
+	default:
+		return &DecodeError{Path: copyPath(path), Err: fmt.Errorf("unsupported type %s", rv.Type())}
+	}
+
+	return nil
+}
+
+func decodeStruct(m map[string]interface{}, rv reflect.Value, path merge.Path) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("configger")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if j := strings.Index(tag, ","); j >= 0 {
+			name, options = tag[:j], tag[j+1:]
+		}
+		if field.Anonymous && !hasTag {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fv := rv.Field(i)
+				if fv.Kind() == reflect.Ptr {
+					if !fv.CanSet() {
+						continue
+					}
+					if fv.IsNil() {
+						fv.Set(reflect.New(ft))
+					}
+					fv = fv.Elem()
+				}
+				if err := decodeStruct(m, fv, path); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		required := false
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case "":
+			case "required":
+				required = true
+			default:
+				return &DecodeError{Path: copyPath(path), Err: fmt.Errorf("invalid tag option '%s' for field %s", option, field.Name)}
+			}
+		}
+		fieldPath := append(path, merge.Segment{Value: name})
+		value := lookupKey(m, name)
+		if value == nil {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				value = def
+			} else if required {
+				return &DecodeError{Path: copyPath(fieldPath), Err: errors.New("missing required value")}
+			}
+		}
+		if err := decodeValue(value, rv.Field(i), fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupKey finds the value for key in m, matching case insensitively if there is no exact match, where the first
+// matching key, in sorted order, wins.
+func lookupKey(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return m[k]
+		}
+	}
+	return nil
+}
+
+func decodeFloat(data interface{}) (float64, error) {
+	switch t := data.(type) {
+	case float64:
+		return t, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(t), 64)
+	}
+	return 0, errors.New("unsupported value")
+}
+
+func decodeDuration(data interface{}) (time.Duration, error) {
+	switch t := data.(type) {
+	case float64:
+		// nanoseconds, like time.Duration itself
+		return time.Duration(t), nil
+	case string:
+		return time.ParseDuration(strings.TrimSpace(t))
+	}
+	return 0, errors.New("unsupported value")
+}
+
+func decodeError(data interface{}, rv reflect.Value, path merge.Path, err error) error {
+	kind := "value"
+	switch data.(type) {
+	case map[string]interface{}:
+		kind = "object"
+	case []interface{}:
+		kind = "array"
+	case string:
+		kind = fmt.Sprintf("string %q", data)
+	case float64:
+		kind = fmt.Sprintf("number %v", data)
+	case bool:
+		kind = fmt.Sprintf("boolean %v", data)
+	}
+	message := fmt.Sprintf("unable to decode %s into %s", kind, rv.Type())
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	return &DecodeError{Path: copyPath(path), Err: errors.New(message)}
+}
+
+func copyPath(path merge.Path) merge.Path {
+	return append(make(merge.Path, 0, len(path)), path...)
+}
@@ -0,0 +1,125 @@
+package configger
+
+import (
+	"github.com/go-test/deep"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	type Embedded struct {
+		Region string
+	}
+	type Target struct {
+		Embedded
+		Host     string            `configger:"host"`
+		Port     uint16            `configger:"port" default:"8080"`
+		Ratio    float32           `configger:"ratio"`
+		Enabled  bool              `configger:"enabled"`
+		Interval time.Duration     `configger:"interval" default:"1m"`
+		Tags     []string          `configger:"tags"`
+		Labels   map[string]string `configger:"labels"`
+		Limit    *int              `configger:"limit"`
+		IP       net.IP            `configger:"ip"`
+		Extra    interface{}       `configger:"extra"`
+		Skipped  string            `configger:"-"`
+		Pair     [2]int            `configger:"pair"`
+		ignored  string
+	}
+	limit := 3
+	expected := Target{
+		Embedded: Embedded{Region: `au`},
+		Host:     `example.com`,
+		Port:     8080,
+		Ratio:    0.5,
+		Enabled:  true,
+		Interval: time.Minute,
+		Tags:     []string{`a`, `1`, `true`},
+		Labels:   map[string]string{`x`: `y`},
+		Limit:    &limit,
+		IP:       net.ParseIP(`10.0.0.1`),
+		Extra:    map[string]interface{}{`k`: []interface{}{float64(1)}},
+		Skipped:  `kept`,
+		Pair:     [2]int{1, 0},
+	}
+	actual := Target{Skipped: `kept`}
+	if err := Decode(parseJSON(t, `{
+  "region": "au",
+  "HOST": "example.com",
+  "ratio": "0.5",
+  "enabled": "true",
+  "tags": ["a", 1, true],
+  "labels": {"x": "y"},
+  "limit": 3,
+  "ip": "10.0.0.1",
+  "extra": {"k": [1]},
+  "Skipped": "nope",
+  "pair": [1],
+  "ignored": "nope"
+}`), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(expected, actual); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestDecode_caseInsensitive(t *testing.T) {
+	var target struct {
+		Host string `configger:"host"`
+		Port int    `configger:"port"`
+	}
+	// the first matching key, in sorted order, wins, e.g. HOST sorts before Host
+	for i := 0; i < 20; i++ {
+		if err := Decode(parseJSON(t, `{"Host": "a", "HOST": "b", "hOst": "c", "port": 1, "PORT": 2}`), &target); err != nil {
+			t.Fatal(err)
+		}
+		if target.Host != `b` || target.Port != 1 {
+			t.Fatalf("unexpected target: %+v", target)
+		}
+	}
+}
+
+func TestDecode_errors(t *testing.T) {
+	type Inner struct {
+		Value int `configger:"value,required"`
+	}
+	type Target struct {
+		Inner   Inner          `configger:"inner"`
+		List    []uint8        `configger:"list"`
+		Timeout time.Duration  `configger:"timeout"`
+		Flag    bool           `configger:"flag"`
+		Pair    [1]string      `configger:"pair"`
+		Map     map[string]int `configger:"map"`
+	}
+	for _, testCase := range []struct {
+		Input string
+		Error string
+	}{
+		{`{}`, `inner.value: missing required value`},
+		{`{"inner": []}`, `inner: unable to decode array into configger.Inner`},
+		{`{"inner": {"value": 1.5}}`, `inner.value: unable to decode number 1.5 into int`},
+		{`{"inner": {"value": 1}, "list": [1, 256]}`, `list[1]: unable to decode number 256 into uint8`},
+		{`{"inner": {"value": 1}, "list": [-1]}`, `list[0]: unable to decode number -1 into uint8`},
+		{`{"inner": {"value": 1}, "timeout": "soon"}`, `timeout: unable to decode string "soon" into time.Duration: time: invalid duration "soon"`},
+		{`{"inner": {"value": 1}, "flag": "maybe"}`, `flag: unable to decode string "maybe" into bool`},
+		{`{"inner": {"value": 1}, "pair": ["a", "b"]}`, `pair: unable to decode array into [1]string: too many elements (2)`},
+		{`{"inner": {"value": 1}, "map": {"a": true}}`, `map.a: unable to decode boolean true into int: unsupported value`},
+	} {
+		var target Target
+		if err := Decode(parseJSON(t, testCase.Input), &target); err == nil || err.Error() != testCase.Error {
+			t.Errorf("%s: unexpected error: %v", testCase.Input, err)
+		}
+	}
+
+	if err := Decode(nil, Target{}); err == nil {
+		t.Error("expected an error")
+	}
+	var bad struct {
+		Value int `configger:"value,optional"`
+	}
+	if err := Decode(nil, &bad); err == nil || err.Error() != `invalid tag option 'optional' for field Value` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
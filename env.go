@@ -0,0 +1,191 @@
+package configger
+
+import (
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"github.com/joeycumines/go-configger/parser"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvCase is how environment variable names are folded into keys, see EnvOptions.
+type EnvCase int
+
+const (
+	// EnvLower folds names to lower case, e.g. APP_DB_HOST is db_host.
+	EnvLower EnvCase = iota
+	// EnvUpper folds names to upper case.
+	EnvUpper
+	// EnvPreserve uses names as they are.
+	EnvPreserve
+)
+
+// EnvOptions configures how environment variables are converted into a config, see EnvValue.
+type EnvOptions struct {
+	// Prefix selects the variables to use, and is removed from each name, e.g. APP_, where an empty prefix selects every
+	// variable.
+	Prefix string
+	// Separator splits names into nested keys, defaulting to a double underscore, e.g. APP_DB__HOST is db.host.
+	Separator string
+	// Case is how each key is folded, unless it matches an existing key (case insensitively).
+	Case EnvCase
+}
+
+// ParseEnvCase parses the name of an EnvCase, one of (lower, upper, preserve).
+func ParseEnvCase(s string) (EnvCase, error) {
+	for _, c := range []EnvCase{EnvLower, EnvUpper, EnvPreserve} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown env case: %s", s)
+}
+
+func (c EnvCase) String() string {
+	switch c {
+	case EnvLower:
+		return "lower"
+	case EnvUpper:
+		return "upper"
+	case EnvPreserve:
+		return "preserve"
+	}
+	return fmt.Sprintf("EnvCase(%d)", int(c))
+}
+
+// EnvValue converts environment variables, in the KEY=value form returned by os.Environ, into a config, which may be
+// merged over base (the config so far).
+//
+// Each key is matched against the keys of the object at the same path in base, case insensitively, using the existing
+// key, if found, and otherwise folding it, see EnvOptions.Case. A key that is an index of an array at the same path in
+// base, e.g. APP_HOSTS__0, addresses that element, where the result contains a merge.Elements, which overrides just the
+// addressed elements, regardless of the array strategy. Values are strings, unless the
+// value at the same path in base is a number or boolean, and the variable may be parsed as one, or an array or object,
+// and the variable is a JSON array or object. Where variables conflict, e.g. APP_DB and APP_DB__HOST, the nested key
+// wins.
+func EnvValue(environ []string, options EnvOptions, base interface{}) interface{} {
+	separator := options.Separator
+	if separator == "" {
+		separator = "__"
+	}
+	environ = append([]string(nil), environ...)
+	sort.Strings(environ)
+	var result interface{} = make(map[string]interface{})
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || i == len(options.Prefix) || !strings.HasPrefix(kv[:i], options.Prefix) {
+			continue
+		}
+		keys := strings.Split(kv[len(options.Prefix):i], separator)
+		if containsEmpty(keys) {
+			continue
+		}
+		result = envSet(result, base, keys, kv[i+1:], options.Case)
+	}
+	return result
+}
+
+// envSet sets value at keys within node, which is nil, or the result of a previous call, returning the result.
+func envSet(node interface{}, base interface{}, keys []string, value string, c EnvCase) interface{} {
+	if len(keys) == 0 {
+		switch node.(type) {
+		case map[string]interface{}, merge.Elements:
+			return node
+		}
+		return coerceEnv(value, base)
+	}
+	if n, ok := envIndex(base, keys[0]); ok {
+		list, ok := node.(merge.Elements)
+		if !ok {
+			list = make(merge.Elements)
+		}
+		list[n] = envSet(list[n], base.([]interface{})[n], keys[1:], value, c)
+		return list
+	}
+	key := envKey(keys[0], base, c)
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+	}
+	m[key] = envSet(m[key], mapValue(base, key), keys[1:], value, c)
+	return m
+}
+
+// envIndex returns the index of the element of base addressed by key, if base is an array, and key is an index of it.
+func envIndex(base interface{}, key string) (int, bool) {
+	list, ok := base.([]interface{})
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(key)
+	if err != nil || strconv.Itoa(n) != key || n < 0 || n >= len(list) {
+		return 0, false
+	}
+	return n, true
+}
+
+// envKey returns the key in base matching key, case insensitively, or key folded using c.
+func envKey(key string, base interface{}, c EnvCase) string {
+	if m, ok := base.(map[string]interface{}); ok {
+		if _, ok := m[key]; ok {
+			return key
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if strings.EqualFold(k, key) {
+				return k
+			}
+		}
+	}
+	switch c {
+	case EnvLower:
+		return strings.ToLower(key)
+	case EnvUpper:
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+func mapValue(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// coerceEnv converts s to the type of base, if possible.
+func coerceEnv(s string, base interface{}) interface{} {
+	switch base.(type) {
+	case float64:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case bool:
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return b
+		}
+	case []interface{}, map[string]interface{}:
+		if v, err := parser.JSONRead(strings.NewReader(s)); err == nil {
+			switch v.(type) {
+			case []interface{}, map[string]interface{}:
+				return v
+			}
+		}
+	}
+	return s
+}
+
+func containsEmpty(keys []string) bool {
+	for _, key := range keys {
+		if key == "" {
+			return true
+		}
+	}
+	return false
+}
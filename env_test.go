@@ -0,0 +1,129 @@
+package configger
+
+import (
+	"encoding/json"
+	"github.com/go-test/deep"
+	"github.com/joeycumines/go-configger/merge"
+	"testing"
+)
+
+func TestEnvValue(t *testing.T) {
+	base := parseJSON(t, `{
+  "db": {"Host": "localhost", "port": 5432, "tls": false},
+  "hosts": ["a"],
+  "name": "base"
+}`)
+	for _, testCase := range []struct {
+		Name     string
+		Environ  []string
+		Options  EnvOptions
+		Base     interface{}
+		Expected string
+		// Value is the expected value, if it can't be represented as JSON
+		Value interface{}
+	}{
+		{
+			Name: `coerced to the base type`,
+			Environ: []string{
+				`APP_DB__HOST=db`,
+				`APP_DB__PORT= 6543 `,
+				`APP_DB__TLS=true`,
+				`APP_HOSTS=["b", "c"]`,
+				`APP_NAME=1`,
+				`APP_NEW=2`,
+				`OTHER=3`,
+				`APP_=4`,
+				`APP_A____B=5`,
+			},
+			Options:  EnvOptions{Prefix: `APP_`},
+			Base:     base,
+			Expected: `{"db": {"Host": "db", "port": 6543, "tls": true}, "hosts": ["b", "c"], "name": "1", "new": "2"}`,
+		},
+		{
+			Name: `unparseable values stay strings`,
+			Environ: []string{
+				`APP_DB__PORT=NaN`,
+				`APP_DB__TLS=yes`,
+				`APP_HOSTS=b`,
+			},
+			Options:  EnvOptions{Prefix: `APP_`},
+			Base:     base,
+			Expected: `{"db": {"port": "NaN", "tls": "yes"}, "hosts": "b"}`,
+		},
+		{
+			Name: `nested keys win`,
+			Environ: []string{
+				`APP_DB__HOST=db`,
+				`APP_DB=x`,
+				`APP_NAME__FIRST=a`,
+			},
+			Options:  EnvOptions{Prefix: `APP_`},
+			Expected: `{"db": {"host": "db"}, "name": {"first": "a"}}`,
+		},
+		{
+			Name:     `separator and case`,
+			Environ:  []string{`X-Db-Host=a`, `X-Other=b`},
+			Options:  EnvOptions{Prefix: `X-`, Separator: `-`, Case: EnvPreserve},
+			Base:     base,
+			Expected: `{"db": {"Host": "a"}, "Other": "b"}`,
+		},
+		{
+			Name:    `array elements`,
+			Environ: []string{`APP_PORTS__1=9`, `APP_HOSTS__01=x`},
+			Options: EnvOptions{Prefix: `APP_`},
+			Base:    parseJSON(t, `{"ports": [1, 2, 3], "hosts": ["a"]}`),
+			Value: map[string]interface{}{
+				`ports`: merge.Elements{1: float64(9)},
+				`hosts`: map[string]interface{}{`01`: `x`},
+			},
+		},
+		{
+			Name:     `out of range elements`,
+			Environ:  []string{`APP_PORTS__3=9`},
+			Options:  EnvOptions{Prefix: `APP_`},
+			Base:     parseJSON(t, `{"ports": [1, 2, 3]}`),
+			Expected: `{"ports": {"3": "9"}}`,
+		},
+		{
+			Name:    `array element objects`,
+			Environ: []string{`APP_SERVERS__0__PORT=81`, `APP_SERVERS__1=x`},
+			Options: EnvOptions{Prefix: `APP_`},
+			Base:    parseJSON(t, `{"servers": [{"host": "a", "port": 80}, "b", "c"]}`),
+			Value: map[string]interface{}{
+				`servers`: merge.Elements{0: map[string]interface{}{`port`: float64(81)}, 1: `x`},
+			},
+		},
+		{
+			Name:     `upper`,
+			Environ:  []string{`db__port=1`, `a__b=c`},
+			Options:  EnvOptions{Case: EnvUpper},
+			Base:     base,
+			Expected: `{"db": {"port": 1}, "A": {"B": "c"}}`,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			before, _ := json.Marshal(testCase.Base)
+			expected := testCase.Value
+			if expected == nil {
+				expected = parseJSON(t, testCase.Expected)
+			}
+			if diff := deep.Equal(expected, EnvValue(testCase.Environ, testCase.Options, testCase.Base)); diff != nil {
+				t.Error(diff)
+			}
+			if after, _ := json.Marshal(testCase.Base); string(before) != string(after) {
+				t.Errorf("base modified: %s", after)
+			}
+		})
+	}
+}
+
+func TestParseEnvCase(t *testing.T) {
+	for _, c := range []EnvCase{EnvLower, EnvUpper, EnvPreserve} {
+		if v, err := ParseEnvCase(c.String()); err != nil || v != c {
+			t.Errorf("%s: %v %v", c, v, err)
+		}
+	}
+	if _, err := ParseEnvCase(`title`); err == nil {
+		t.Error("expected an error")
+	}
+}
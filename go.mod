@@ -7,4 +7,5 @@ require (
 	github.com/joho/godotenv v1.5.1
 	gopkg.in/urfave/cli.v1 v1.20.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
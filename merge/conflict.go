@@ -0,0 +1,122 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy controls how Merge handles a change in the kind of value at a path, e.g. where the base has an
+// object, and the overlay a string. Kinds are one of object, array, or scalar, and null never conflicts.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the base value with the overlay value, as with any other merge.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictError fails the merge.
+	ConflictError
+	// ConflictKeepBase ignores the overlay value, keeping the base value.
+	ConflictKeepBase
+)
+
+var conflictPolicies = map[ConflictPolicy]string{
+	ConflictOverwrite: "overwrite",
+	ConflictError:     "error",
+	ConflictKeepBase:  "keep-base",
+}
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for policy, name := range conflictPolicies {
+		if strings.EqualFold(s, name) {
+			return policy, nil
+		}
+	}
+	return ConflictOverwrite, fmt.Errorf("unknown conflict policy: %s", s)
+}
+
+func (p ConflictPolicy) String() string {
+	if name, ok := conflictPolicies[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Conflict is a change in the kind of value at a path, between the base and an overlay.
+type Conflict struct {
+	Path Path
+	// Base and Overlay are the kinds of each value, one of object, array, or scalar.
+	Base    string
+	Overlay string
+	// BaseSource describes the origins of the base value, and OverlaySource is the source being merged, see
+	// Mode.Source, either may be empty if unknown.
+	BaseSource    string
+	OverlaySource string
+	// Policy is the policy that was applied.
+	Policy ConflictPolicy
+}
+
+func (c Conflict) Error() string {
+	return fmt.Sprintf("conflict at %s: %s%s and %s%s", c.location(), c.Base, fromSource(c.BaseSource), c.Overlay, fromSource(c.OverlaySource))
+}
+
+// Warning describes the resolution of the conflict.
+func (c Conflict) Warning() string {
+	switch c.Policy {
+	case ConflictKeepBase:
+		return fmt.Sprintf("conflict at %s: kept %s%s, ignoring %s%s", c.location(), c.Base, fromSource(c.BaseSource), c.Overlay, fromSource(c.OverlaySource))
+	case ConflictError:
+		return c.Error()
+	default:
+		return fmt.Sprintf("conflict at %s: replaced %s%s with %s%s", c.location(), c.Base, fromSource(c.BaseSource), c.Overlay, fromSource(c.OverlaySource))
+	}
+}
+
+func (c Conflict) location() string {
+	if len(c.Path) == 0 {
+		return "the root"
+	}
+	return c.Path.String()
+}
+
+func fromSource(source string) string {
+	if source == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (from %s)", source)
+}
+
+// conflict checks for a conflict between a base value a and overlay value b, at path, returning true if the base
+// should be kept, or an error if the merge should fail, recording any conflict found.
+func (m *Mode) conflict(a, b interface{}, path Path) (bool, error) {
+	if a == nil || b == nil || kindOf(a) == kindOf(b) {
+		return false, nil
+	}
+	c := Conflict{
+		Path:          append(make(Path, 0, len(path)), path...),
+		Base:          kindOf(a),
+		Overlay:       kindOf(b),
+		BaseSource:    m.origin(a, path),
+		OverlaySource: m.Source,
+		Policy:        m.Conflict,
+	}
+	m.Conflicts = append(m.Conflicts, c)
+	switch m.Conflict {
+	case ConflictError:
+		return false, c
+	case ConflictKeepBase:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// kindOf describes the structural kind of a value, one of object, array, or scalar.
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}, Elements:
+		return "array"
+	default:
+		return "scalar"
+	}
+}
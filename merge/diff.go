@@ -0,0 +1,141 @@
+package merge
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// ChangeType is the type of a Change, see Diff.
+type ChangeType int
+
+const (
+	// Added indicates that a path only exists in the second value.
+	Added ChangeType = iota + 1
+	// Removed indicates that a path only exists in the first value.
+	Removed
+	// Changed indicates that a path exists in both values, with different values.
+	Changed
+)
+
+// Change is a single difference between two values, see Diff.
+type Change struct {
+	Path Path
+	Type ChangeType
+	// Old is the value in the first value, unless the type is Added.
+	Old interface{}
+	// New is the value in the second value, unless the type is Removed.
+	New interface{}
+}
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(t))
+}
+
+// Diff returns the differences between a and b, ordered by path, where objects are compared by key, and arrays by
+// index, and a value of a different kind (object, array, or scalar) is a single change.
+func Diff(a, b interface{}) []Change {
+	var changes []Change
+	diff(a, b, make(Path, 0), &changes)
+	return changes
+}
+
+func diff(a, b interface{}, path Path, changes *[]Change) {
+	switch tA := a.(type) {
+	case map[string]interface{}:
+		tB, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(tA)+len(tB))
+		for k := range tA {
+			keys = append(keys, k)
+		}
+		for k := range tB {
+			if _, ok := tA[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vA, okA := tA[k]
+			vB, okB := tB[k]
+			p := appendSegment(path, Segment{Value: k})
+			switch {
+			case !okA:
+				*changes = append(*changes, Change{Path: p, Type: Added, New: vB})
+			case !okB:
+				*changes = append(*changes, Change{Path: p, Type: Removed, Old: vA})
+			default:
+				diff(vA, vB, p, changes)
+			}
+		}
+		return
+
+	case []interface{}:
+		tB, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(tA) || i < len(tB); i++ {
+			p := appendSegment(path, Segment{Value: strconv.Itoa(i), Index: true})
+			switch {
+			case i >= len(tA):
+				*changes = append(*changes, Change{Path: p, Type: Added, New: tB[i]})
+			case i >= len(tB):
+				*changes = append(*changes, Change{Path: p, Type: Removed, Old: tA[i]})
+			default:
+				diff(tA[i], tB[i], p, changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: copyPath(path), Type: Changed, Old: a, New: b})
+	}
+}
+
+// CreateJSONPatch returns a JSON Patch (RFC 6902) that transforms a into b, when applied using ApplyJSONPatch, as an
+// array of operations, see Diff.
+func CreateJSONPatch(a, b interface{}) interface{} {
+	changes := Diff(a, b)
+	patch := make([]interface{}, 0, len(changes))
+	for i := 0; i < len(changes); i++ {
+		change := changes[i]
+		switch change.Type {
+		case Added:
+			patch = append(patch, map[string]interface{}{"op": "add", "path": FormatPointer(change.Path), "value": change.New})
+		case Changed:
+			patch = append(patch, map[string]interface{}{"op": "replace", "path": FormatPointer(change.Path), "value": change.New})
+		case Removed:
+			// trailing array elements must be removed from the end, so each index remains valid
+			j := i
+			for j+1 < len(changes) && changes[j+1].Type == Removed && isTrailingElement(change.Path, changes[j+1].Path) {
+				j++
+			}
+			for k := j; k >= i; k-- {
+				patch = append(patch, map[string]interface{}{"op": "remove", "path": FormatPointer(changes[k].Path)})
+			}
+			i = j
+		}
+	}
+	return patch
+}
+
+// isTrailingElement returns true if both paths are elements of the same array.
+func isTrailingElement(a, b Path) bool {
+	if len(a) == 0 || len(a) != len(b) || !a[len(a)-1].Index || !b[len(b)-1].Index {
+		return false
+	}
+	return reflect.DeepEqual(a[:len(a)-1], b[:len(b)-1])
+}
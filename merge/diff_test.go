@@ -0,0 +1,71 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type change struct {
+		Path     string
+		Type     ChangeType
+		Old, New interface{}
+	}
+	for _, testCase := range []struct {
+		A, B    string
+		Changes []change
+	}{
+		{`{"a":1}`, `{"a":1}`, nil},
+		{`null`, `null`, nil},
+		{`{"a":1}`, `{"a":2}`, []change{{"a", Changed, 1.0, 2.0}}},
+		{`{"a":1}`, `{"a":1,"b":"c"}`, []change{{"b", Added, nil, "c"}}},
+		{`{"a":1,"b":{"c":true}}`, `{"a":1}`, []change{{"b", Removed, map[string]interface{}{"c": true}, nil}}},
+		{
+			`{"b":{"x":1,"y":2},"a":[1,2,3]}`,
+			`{"b":{"y":3,"z":4},"a":[1,5]}`,
+			[]change{
+				{"a[1]", Changed, 2.0, 5.0},
+				{"a[2]", Removed, 3.0, nil},
+				{"b.x", Removed, 1.0, nil},
+				{"b.y", Changed, 2.0, 3.0},
+				{"b.z", Added, nil, 4.0},
+			},
+		},
+		{`{"a":[1]}`, `{"a":[1,{"b":null}]}`, []change{{"a[1]", Added, nil, map[string]interface{}{"b": nil}}}},
+		{`{"a":[1]}`, `{"a":{"0":1}}`, []change{{"a", Changed, []interface{}{1.0}, map[string]interface{}{"0": 1.0}}}},
+		{`{"a.b":1}`, `{"a.b":null}`, []change{{`["a.b"]`, Changed, 1.0, nil}}},
+		{`[1]`, `"a"`, []change{{"", Changed, []interface{}{1.0}, "a"}}},
+	} {
+		var changes []change
+		for _, c := range Diff(parseJSON(t, testCase.A), parseJSON(t, testCase.B)) {
+			changes = append(changes, change{c.Path.String(), c.Type, c.Old, c.New})
+		}
+		if diff := deep.Equal(testCase.Changes, changes); diff != nil {
+			t.Errorf("%s %s: %v", testCase.A, testCase.B, diff)
+		}
+	}
+}
+
+func TestCreateJSONPatch(t *testing.T) {
+	for _, testCase := range []struct {
+		A, B, Patch string
+	}{
+		{`{"a":1}`, `{"a":1}`, `[]`},
+		{`{"a":1}`, `{"a":2,"b/c":3}`, `[{"op":"replace","path":"/a","value":2},{"op":"add","path":"/b~1c","value":3}]`},
+		{`{"a":[1,2,3,4],"b":1}`, `{"a":[0]}`, `[{"op":"replace","path":"/a/0","value":0},{"op":"remove","path":"/a/3"},{"op":"remove","path":"/a/2"},{"op":"remove","path":"/a/1"},{"op":"remove","path":"/b"}]`},
+		{`{"a":[1]}`, `{"a":[1,2,3]}`, `[{"op":"add","path":"/a/1","value":2},{"op":"add","path":"/a/2","value":3}]`},
+		{`{"a":[[1,2],[3,4]]}`, `{"a":[[1],[3]]}`, `[{"op":"remove","path":"/a/0/1"},{"op":"remove","path":"/a/1/1"}]`},
+		{`{"a":1}`, `[1]`, `[{"op":"replace","path":"","value":[1]}]`},
+	} {
+		a, b := parseJSON(t, testCase.A), parseJSON(t, testCase.B)
+		patch := CreateJSONPatch(a, b)
+		if diff := deep.Equal(parseJSON(t, testCase.Patch), patch); diff != nil {
+			t.Errorf("%s %s: %v", testCase.A, testCase.B, diff)
+		}
+		if patched, err := ApplyJSONPatch(a, patch); err != nil {
+			t.Errorf("%s %s: %v", testCase.A, testCase.B, err)
+		} else if diff := deep.Equal(b, patched); diff != nil {
+			t.Errorf("%s %s: applied: %v", testCase.A, testCase.B, diff)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ApplyJSONPatch applies a JSON Patch (RFC 6902) document, which must be an array of operations, to doc, returning
+// the result, without modifying doc. Operations are applied in order, and any failure (including a failed test
+// operation) aborts the whole patch.
+func ApplyJSONPatch(doc interface{}, patch interface{}) (interface{}, error) {
+	operations, ok := patch.([]interface{})
+	if !ok {
+		return nil, errors.New("json patch must be an array of operations")
+	}
+
+	doc = deepCopy(doc)
+
+	for i, v := range operations {
+		operation, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json patch operation %d: must be an object", i)
+		}
+		var err error
+		doc, err = applyJSONPatchOperation(doc, operation)
+		if err != nil {
+			op, _ := operation["op"].(string)
+			p, _ := operation["path"].(string)
+			return nil, fmt.Errorf("json patch operation %d (%s %q): %s", i, op, p, err.Error())
+		}
+	}
+
+	return doc, nil
+}
+
+// Patch applies a JSON Patch to v, see ApplyJSONPatch, returning a new value with anything not included removed. Any
+// values that were added or changed are attributed to the current Source, see Explain.
+func (m *Mode) Patch(v interface{}, patch interface{}) (interface{}, error) {
+	result, err := ApplyJSONPatch(v, patch)
+	if err != nil {
+		return nil, err
+	}
+	result = m.filter(result, make(Path, 0), nil)
+	m.repatch(v, result)
+	return result, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, operation map[string]interface{}) (interface{}, error) {
+	member := func(name string) (string, error) {
+		v, ok := operation[name]
+		if !ok {
+			return "", fmt.Errorf("missing %q", name)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("invalid %q: %v", name, v)
+		}
+		return s, nil
+	}
+
+	op, err := member("op")
+	if err != nil {
+		return nil, err
+	}
+	p, err := member("path")
+	if err != nil {
+		return nil, err
+	}
+	path, err := ParsePointer(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "add", "replace", "test":
+		value, ok := operation["value"]
+		if !ok {
+			return nil, errors.New(`missing "value"`)
+		}
+		switch op {
+		case "add":
+			return pointerAdd(doc, path, deepCopy(value))
+		case "replace":
+			if _, err := pointerGet(doc, path); err != nil {
+				return nil, err
+			}
+			if doc, _, err = pointerRemove(doc, path, true); err != nil {
+				return nil, err
+			}
+			return pointerAdd(doc, path, deepCopy(value))
+		default:
+			actual, err := pointerGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(actual, value) {
+				return nil, fmt.Errorf("test failed, value is %v", actual)
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err = pointerRemove(doc, path, false)
+		return doc, err
+
+	case "move", "copy":
+		f, err := member("from")
+		if err != nil {
+			return nil, err
+		}
+		from, err := ParsePointer(f)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op == "copy" {
+			if value, err = pointerGet(doc, from); err != nil {
+				return nil, err
+			}
+			value = deepCopy(value)
+		} else {
+			if len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+				return nil, errors.New("unable to move a value into one of its children")
+			}
+			if doc, value, err = pointerRemove(doc, from, false); err != nil {
+				return nil, err
+			}
+		}
+		return pointerAdd(doc, path, value)
+
+	default:
+		return nil, fmt.Errorf("unknown op %q", op)
+	}
+}
+
+// ParsePointer parses a JSON Pointer (RFC 6901), e.g. "/a/b~1c/0", where an empty string is the root.
+func ParsePointer(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid json pointer %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j == len(token)-1 || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid json pointer %q", s)
+			}
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// FormatPointer formats a path as a JSON Pointer (RFC 6901).
+func FormatPointer(path Path) string {
+	var b strings.Builder
+	for _, segment := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment.Value))
+	}
+	return b.String()
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for i, token := range path {
+		switch t := doc.(type) {
+		case map[string]interface{}:
+			v, ok := t[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found at %s", formatPointerTokens(path[:i+1]))
+			}
+			doc = v
+		case []interface{}:
+			index, err := pointerIndex(token, len(t)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s at %s", err.Error(), formatPointerTokens(path[:i+1]))
+			}
+			doc = t[index]
+		default:
+			return nil, fmt.Errorf("path not found at %s", formatPointerTokens(path[:i+1]))
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd adds value at path, returning the modified doc, which may be modified in place.
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch t := parent.(type) {
+	case map[string]interface{}:
+		t[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(t)
+		if token != "-" {
+			if index, err = pointerIndex(token, len(t)); err != nil {
+				return nil, fmt.Errorf("%s at %s", err.Error(), formatPointerTokens(path))
+			}
+		}
+		t = append(t, nil)
+		copy(t[index+1:], t[index:])
+		t[index] = value
+		return pointerSet(doc, path[:len(path)-1], t), nil
+	default:
+		return nil, fmt.Errorf("unable to add to a %s at %s", kindOf(parent), formatPointerTokens(path[:len(path)-1]))
+	}
+}
+
+// pointerRemove removes the value at path, returning the modified doc, and the removed value. The root may only be
+// removed if it will be replaced.
+func pointerRemove(doc interface{}, path []string, replace bool) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		if replace {
+			return nil, doc, nil
+		}
+		return nil, nil, errors.New("unable to remove the root")
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+	switch t := parent.(type) {
+	case map[string]interface{}:
+		v, ok := t[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found at %s", formatPointerTokens(path))
+		}
+		delete(t, token)
+		return doc, v, nil
+	case []interface{}:
+		index, err := pointerIndex(token, len(t)-1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s at %s", err.Error(), formatPointerTokens(path))
+		}
+		v := t[index]
+		t = append(t[:index], t[index+1:]...)
+		return pointerSet(doc, path[:len(path)-1], t), v, nil
+	default:
+		return nil, nil, fmt.Errorf("path not found at %s", formatPointerTokens(path))
+	}
+}
+
+// pointerSet replaces the (existing) value at path, returning the modified doc.
+func pointerSet(doc interface{}, path []string, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+	parent, _ := pointerGet(doc, path[:len(path)-1])
+	token := path[len(path)-1]
+	switch t := parent.(type) {
+	case map[string]interface{}:
+		t[token] = value
+	case []interface{}:
+		index, _ := strconv.Atoi(token)
+		t[index] = value
+	}
+	return doc
+}
+
+// pointerIndex parses an array index, which may not exceed max.
+func pointerIndex(token string, max int) (int, error) {
+	if !isIndex(token) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, fmt.Errorf("array index %s out of bounds", token)
+	}
+	return index, nil
+}
+
+func formatPointerTokens(tokens []string) string {
+	path := make(Path, len(tokens))
+	for i, token := range tokens {
+		path[i] = Segment{Value: token}
+	}
+	return FormatPointer(path)
+}
+
+// deepCopy copies maps and arrays recursively.
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			result[k] = deepCopy(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, v := range t {
+			result[i] = deepCopy(v)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,97 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	for _, testCase := range []struct {
+		Name, Doc, Patch, Expected string
+		Error                      bool
+	}{
+		// RFC 6902 appendix A
+		{Name: `A.1`, Doc: `{"foo":"bar"}`, Patch: `[{"op":"add","path":"/baz","value":"qux"}]`, Expected: `{"baz":"qux","foo":"bar"}`},
+		{Name: `A.2`, Doc: `{"foo":["bar","baz"]}`, Patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, Expected: `{"foo":["bar","qux","baz"]}`},
+		{Name: `A.3`, Doc: `{"baz":"qux","foo":"bar"}`, Patch: `[{"op":"remove","path":"/baz"}]`, Expected: `{"foo":"bar"}`},
+		{Name: `A.4`, Doc: `{"foo":["bar","qux","baz"]}`, Patch: `[{"op":"remove","path":"/foo/1"}]`, Expected: `{"foo":["bar","baz"]}`},
+		{Name: `A.5`, Doc: `{"baz":"qux","foo":"bar"}`, Patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, Expected: `{"baz":"boo","foo":"bar"}`},
+		{Name: `A.6`, Doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, Patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, Expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{Name: `A.7`, Doc: `{"foo":["all","grass","cows","eat"]}`, Patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, Expected: `{"foo":["all","cows","eat","grass"]}`},
+		{Name: `A.8`, Doc: `{"baz":"qux","foo":["a",2,"c"]}`, Patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, Expected: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{Name: `A.9`, Doc: `{"baz":"qux"}`, Patch: `[{"op":"test","path":"/baz","value":"bar"}]`, Error: true},
+		{Name: `A.10`, Doc: `{"foo":"bar"}`, Patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, Expected: `{"foo":"bar","child":{"grandchild":{}}}`},
+		{Name: `A.11`, Doc: `{"foo":"bar"}`, Patch: `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, Expected: `{"foo":"bar","baz":"qux"}`},
+		{Name: `A.12`, Doc: `{"foo":"bar"}`, Patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, Error: true},
+		{Name: `A.14`, Doc: `{"/":9,"~1":10}`, Patch: `[{"op":"test","path":"/~01","value":10}]`, Expected: `{"/":9,"~1":10}`},
+		{Name: `A.15`, Doc: `{"/":9,"~1":10}`, Patch: `[{"op":"test","path":"/~01","value":"10"}]`, Error: true},
+		{Name: `A.16`, Doc: `{"foo":["bar"]}`, Patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, Expected: `{"foo":["bar",["abc","def"]]}`},
+		// other cases
+		{Name: `add root`, Doc: `{"a":1}`, Patch: `[{"op":"add","path":"","value":[1]}]`, Expected: `[1]`},
+		{Name: `add to null`, Doc: `null`, Patch: `[{"op":"add","path":"","value":{}},{"op":"add","path":"/a","value":1}]`, Expected: `{"a":1}`},
+		{Name: `replace root`, Doc: `{"a":1}`, Patch: `[{"op":"replace","path":"","value":2}]`, Expected: `2`},
+		{Name: `replace last`, Doc: `[1,2]`, Patch: `[{"op":"replace","path":"/1","value":3}]`, Expected: `[1,3]`},
+		{Name: `replace missing`, Doc: `{"a":1}`, Patch: `[{"op":"replace","path":"/b","value":3}]`, Error: true},
+		{Name: `remove root`, Doc: `{"a":1}`, Patch: `[{"op":"remove","path":""}]`, Error: true},
+		{Name: `remove dash`, Doc: `[1]`, Patch: `[{"op":"remove","path":"/-"}]`, Error: true},
+		{Name: `add out of bounds`, Doc: `[1]`, Patch: `[{"op":"add","path":"/2","value":2}]`, Error: true},
+		{Name: `add leading zero`, Doc: `[1]`, Patch: `[{"op":"add","path":"/01","value":2}]`, Error: true},
+		{Name: `add to scalar`, Doc: `{"a":1}`, Patch: `[{"op":"add","path":"/a/b","value":2}]`, Error: true},
+		{Name: `copy`, Doc: `{"a":{"b":[1]}}`, Patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`, Expected: `{"a":{"b":[1]},"c":{"b":[1,2]}}`},
+		{Name: `move to child`, Doc: `{"a":{"b":1}}`, Patch: `[{"op":"move","from":"/a","path":"/a/c"}]`, Error: true},
+		{Name: `move to self`, Doc: `{"a":{"b":1}}`, Patch: `[{"op":"move","from":"/a","path":"/a"}]`, Expected: `{"a":{"b":1}}`},
+		{Name: `nested array`, Doc: `{"a":[[1],[2]]}`, Patch: `[{"op":"add","path":"/a/1/0","value":0},{"op":"remove","path":"/a/0/0"}]`, Expected: `{"a":[[],[0,2]]}`},
+		{Name: `unknown op`, Doc: `{}`, Patch: `[{"op":"merge","path":"/a"}]`, Error: true},
+		{Name: `missing value`, Doc: `{}`, Patch: `[{"op":"add","path":"/a"}]`, Error: true},
+		{Name: `bad pointer`, Doc: `{}`, Patch: `[{"op":"add","path":"a","value":1}]`, Error: true},
+		{Name: `bad escape`, Doc: `{}`, Patch: `[{"op":"add","path":"/a~2","value":1}]`, Error: true},
+		{Name: `not an array`, Doc: `{}`, Patch: `{"op":"add","path":"/a","value":1}`, Error: true},
+		{Name: `atomic`, Doc: `{"a":1}`, Patch: `[{"op":"add","path":"/b","value":2},{"op":"test","path":"/a","value":2}]`, Error: true},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			doc := parseJSON(t, testCase.Doc)
+			result, err := ApplyJSONPatch(doc, parseJSON(t, testCase.Patch))
+			if testCase.Error {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", result)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if diff := deep.Equal(parseJSON(t, testCase.Expected), result); diff != nil {
+				t.Error(diff)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Doc), doc); diff != nil {
+				t.Errorf("modified the input: %v", diff)
+			}
+		})
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	for _, testCase := range []struct {
+		S        string
+		Expected []string
+	}{
+		{``, []string{}},
+		{`/`, []string{``}},
+		{`/a/b`, []string{`a`, `b`}},
+		{`/a~1b/~0c/~01`, []string{`a/b`, `~c`, `~1`}},
+		{`/a//b`, []string{`a`, ``, `b`}},
+	} {
+		tokens, err := ParsePointer(testCase.S)
+		if err != nil {
+			t.Errorf("%q: %v", testCase.S, err)
+		} else if diff := deep.Equal(testCase.Expected, tokens); diff != nil {
+			t.Errorf("%q: %v", testCase.S, diff)
+		}
+		if testCase.S != "" {
+			path := make(Path, len(tokens))
+			for i, token := range tokens {
+				path[i] = Segment{Value: token}
+			}
+			if s := FormatPointer(path); s != testCase.S {
+				t.Errorf("%q: formatted as %q", testCase.S, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package merge
+
+import (
+	"reflect"
+)
+
+// CreateMergePatch returns a JSON Merge Patch (RFC 7386) that transforms a into b, when applied using Mode.Merge,
+// with MergePatch enabled (and no other options).
+//
+// As per the RFC, it's not possible to set values to null, and any such keys in b will be removed instead.
+func CreateMergePatch(a, b interface{}) interface{} {
+	tA, okA := a.(map[string]interface{})
+	tB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		return b
+	}
+
+	patch := make(map[string]interface{})
+	for k := range tA {
+		if _, ok := tB[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, vB := range tB {
+		vA, ok := tA[k]
+		if !ok {
+			patch[k] = vB
+			continue
+		}
+		if reflect.DeepEqual(vA, vB) {
+			continue
+		}
+		patch[k] = CreateMergePatch(vA, vB)
+	}
+	return patch
+}
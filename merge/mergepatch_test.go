@@ -0,0 +1,40 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestCreateMergePatch(t *testing.T) {
+	for _, testCase := range []struct {
+		A, B, Patch string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"a":"b","b":"c"}`, `{"b":"c"}`},
+		{`{"a":"b","b":"c"}`, `{"b":"c"}`, `{"a":null}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c","d":{"e":1}}}`, `{"a":{"b":"d","d":{"e":1}}}`, `{"a":{"b":"d"}}`},
+		{`{"a":{"b":"c"}}`, `{"a":{}}`, `{"a":{"b":null}}`},
+		{`{"a":[1,2]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"a":1}`, `{"a":1}`, `{}`},
+		{`["a"]`, `{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+		{`{"a":1}`, `null`, `null`},
+	} {
+		a, b := parseJSON(t, testCase.A), parseJSON(t, testCase.B)
+		patch := CreateMergePatch(a, b)
+		if diff := deep.Equal(parseJSON(t, testCase.Patch), patch); diff != nil {
+			t.Errorf("%s %s: %v", testCase.A, testCase.B, diff)
+		}
+		mode := NewMode()
+		mode.MergePatch = true
+		if merged, err := mode.Merge(a, patch); err != nil {
+			t.Errorf("%s %s: %v", testCase.A, testCase.B, err)
+		} else if diff := deep.Equal(b, merged); diff != nil {
+			t.Errorf("%s %s: applied: %v", testCase.A, testCase.B, diff)
+		}
+	}
+}
+
+// TestMode_Merge_arrayExclusions checks every combination of base and overlay lengths and excluded indexes, for each
+// array strategy, against a simple model, where each input is filtered before merging.
@@ -0,0 +1,638 @@
+// Package merge implements the merging of parsed configs, as used by the goconfigger command, including filtering by
+// path, array strategies, JSON Merge Patch and JSON Patch, conflict detection, and provenance.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node configures the handling of the paths matching Path, within a Mode, see Mode.Define.
+type Node struct {
+	// Path is the path passed to Define, which must not be modified
+	Path      string
+	Whitelist bool
+	Blacklist bool
+	// Array is the strategy for arrays matching this path, overriding the Mode default, unless it is ArrayDefault
+	Array ArrayStrategy
+	// ArrayKey is the key field used by ArrayMergeByKey
+	ArrayKey string
+	pattern  Path
+}
+
+// Elements is an overlay for an array, which merges each value into the element of the base at the same index,
+// regardless of the array strategy, e.g. to override a single element, like Set. Any index that doesn't exist in the
+// base is ignored, and Filter results in an empty array.
+type Elements map[int]interface{}
+
+// Mode controls which paths are included by Merge.
+//
+// Paths use the syntax described by Path, and may contain glob-style segments, such as "services.*.password" or
+// "**.secret", see matchSegment.
+//
+// If there are no whitelisted paths, everything that isn't blacklisted is included, otherwise only whitelisted
+// subtrees (and the maps or arrays containing them, if anything within them is included) are. Where both apply to a
+// path, the entry matching the deepest (most specific) part of it wins, so blacklisted paths may exclude parts of a
+// whitelisted subtree, and vice versa. Entries matching at the same depth are ranked by their number of segments
+// without wildcards, preferring the whitelist if they are equal.
+//
+// Arrays are merged using the strategy of the most specific node matching their path, or the Mode default. Array
+// indexes in paths refer to the position of an element within each input, and excluded elements are removed from (or
+// replaced with null within) each input, before it is merged, see ArrayExclusion.
+//
+// Changes in the kind of value at a path (object, array, or scalar) are handled according to the Conflict policy,
+// and recorded in Conflicts, identifying the source of each value, see Source.
+//
+// The provenance of every leaf value is recorded as it is merged, see Explain.
+type Mode struct {
+	// nodes are the paths configured via Define, by path
+	nodes map[string]*Node
+	// Array is the default strategy for merging arrays, see ArrayStrategy.
+	Array ArrayStrategy
+	// ArrayKey is the key field used by ArrayMergeByKey, if it is the default strategy.
+	ArrayKey string
+	// ArrayExclusion controls whether excluded array elements are removed, or replaced with null.
+	ArrayExclusion ArrayExclusion
+	// MergePatch enables JSON Merge Patch (RFC 7386) semantics, where a null in an overlay removes the key from the
+	// base, and arrays are replaced, unless configured otherwise.
+	MergePatch bool
+	// Conflict is the policy for changes in the kind of value at a path.
+	Conflict ConflictPolicy
+	// Conflicts are all conflicts found, in order.
+	Conflicts []Conflict
+	// Source identifies the value being merged (or filtered), e.g. a file name, recorded as the origin of any paths
+	// it sets, see Explain.
+	Source string
+	// SourceLines optionally maps paths of the value being merged (formatted using Path.String) to line numbers.
+	SourceLines map[string]int
+	provenance  map[string]*Provenance
+}
+
+// ArrayExclusion controls what happens to the positions of array elements, when some are excluded.
+type ArrayExclusion int
+
+const (
+	// ArrayCompact removes excluded elements, such that later elements (of each input) move to fill the gap.
+	ArrayCompact ArrayExclusion = iota
+	// ArrayPreserve replaces excluded elements with null, such that every element keeps its position.
+	ArrayPreserve
+)
+
+var arrayExclusions = map[ArrayExclusion]string{
+	ArrayCompact:  "compact",
+	ArrayPreserve: "preserve",
+}
+
+func ParseArrayExclusion(s string) (ArrayExclusion, error) {
+	for exclusion, name := range arrayExclusions {
+		if strings.EqualFold(s, name) {
+			return exclusion, nil
+		}
+	}
+	return ArrayCompact, fmt.Errorf("unknown array exclusion: %s", s)
+}
+
+func (e ArrayExclusion) String() string {
+	if name, ok := arrayExclusions[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ArrayStrategy controls how an array from an overlay is merged with an array from the base.
+type ArrayStrategy int
+
+const (
+	// ArrayDefault is the zero value, and defers to the Mode default, which defaults to ArrayIndexMerge, or
+	// ArrayReplace, if MergePatch is enabled.
+	ArrayDefault ArrayStrategy = iota
+	// ArrayIndexMerge replaces elements at each index of the base with those from the overlay, retaining any extra.
+	ArrayIndexMerge
+	// ArrayReplace replaces the base with the overlay.
+	ArrayReplace
+	// ArrayAppend appends the overlay to the base.
+	ArrayAppend
+	// ArrayPrepend prepends the overlay to the base.
+	ArrayPrepend
+	// ArrayUniqueUnion appends the overlay to the base, then removes any duplicate elements, keeping the first.
+	ArrayUniqueUnion
+	// ArrayMergeByKey merges elements of the overlay into the elements of the base with an equal value for a key
+	// field (e.g. "name"), in place, appending any that don't match, like a Kubernetes strategic merge patch.
+	ArrayMergeByKey
+)
+
+var arrayStrategies = map[ArrayStrategy]string{
+	ArrayIndexMerge:  "index-merge",
+	ArrayReplace:     "replace",
+	ArrayAppend:      "append",
+	ArrayPrepend:     "prepend",
+	ArrayUniqueUnion: "unique-union",
+	ArrayMergeByKey:  "merge-by-key",
+}
+
+func ParseArrayStrategy(s string) (ArrayStrategy, error) {
+	for strategy, name := range arrayStrategies {
+		if strings.EqualFold(s, name) {
+			return strategy, nil
+		}
+	}
+	return ArrayDefault, fmt.Errorf("unknown array strategy: %s", s)
+}
+
+func (s ArrayStrategy) String() string {
+	if name, ok := arrayStrategies[s]; ok {
+		return name
+	}
+	return "default"
+}
+
+type inclusion int
+
+const (
+	excluded inclusion = iota
+	// partial is an ancestor of a whitelisted path, which is included only as a container for it
+	partial
+	included
+)
+
+func NewMode() *Mode {
+	return &Mode{nodes: make(map[string]*Node)}
+}
+
+func (m *Mode) Included(path Path) bool {
+	return m.include(path) != excluded
+}
+
+// Define returns the node for the path s, parsing it if it hasn't already been defined.
+func (m *Mode) Define(s string) (*Node, error) {
+	if node, ok := m.nodes[s]; ok {
+		return node, nil
+	}
+	pattern, err := ParsePath(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(pattern) == 0 {
+		return nil, errors.New("invalid path: empty")
+	}
+	node := &Node{
+		Path:    s,
+		pattern: pattern,
+	}
+	if m.nodes == nil {
+		m.nodes = make(map[string]*Node)
+	}
+	m.nodes[s] = node
+	return node, nil
+}
+
+func (m *Mode) include(path Path) inclusion {
+	if len(m.nodes) == 0 {
+		return included
+	}
+
+	var blacklisted bool
+	for i := len(path); i > 0; i-- {
+		var best *Node
+		for _, node := range m.nodes {
+			if (!node.Whitelist && !node.Blacklist) || !matchPath(node.pattern, path[:i]) {
+				continue
+			}
+			if best == nil ||
+				specificity(node.pattern) > specificity(best.pattern) ||
+				(specificity(node.pattern) == specificity(best.pattern) && node.Whitelist) {
+				best = node
+			}
+		}
+		if best != nil {
+			if best.Whitelist {
+				return included
+			}
+			blacklisted = true
+			break
+		}
+	}
+
+	var whitelist bool
+	for _, node := range m.nodes {
+		if !node.Whitelist {
+			continue
+		}
+		if matchDescendant(node.pattern, path) {
+			return partial
+		}
+		whitelist = true
+	}
+
+	if blacklisted || whitelist {
+		return excluded
+	}
+	return included
+}
+
+// includeValue applies the inclusion for a value at path, where partially included paths only retain containers,
+// which must contain something included, unless they were already empty, e.g. a whitelisted path that doesn't exist
+// doesn't result in an empty container.
+func (m *Mode) includeValue(path Path, v interface{}) bool {
+	switch m.include(path) {
+	case included:
+		return true
+	case partial:
+		switch t := v.(type) {
+		case map[string]interface{}:
+			return len(t) == 0 || len(m.filter(t, path, nil).(map[string]interface{})) != 0
+		case []interface{}:
+			return len(t) == 0 || len(m.filter(t, path, nil).([]interface{})) != 0
+		}
+	}
+	return false
+}
+
+// arrayStrategy resolves the strategy for an array at path, and the key field, for ArrayMergeByKey.
+func (m *Mode) arrayStrategy(path Path) (ArrayStrategy, string) {
+	var best *Node
+	for _, node := range m.nodes {
+		if node.Array == ArrayDefault || !matchPath(node.pattern, path) {
+			continue
+		}
+		if best == nil ||
+			specificity(node.pattern) > specificity(best.pattern) ||
+			(specificity(node.pattern) == specificity(best.pattern) && node.Path > best.Path) {
+			best = node
+		}
+	}
+	if best != nil {
+		return best.Array, best.ArrayKey
+	}
+	if m.Array != ArrayDefault {
+		return m.Array, m.ArrayKey
+	}
+	if m.MergePatch {
+		return ArrayReplace, ""
+	}
+	return ArrayIndexMerge, ""
+}
+
+// elements filters the elements of an array, at src, where any excluded elements are removed, or replaced with null
+// if ArrayExclusion is ArrayPreserve. If dst is not nil, the provenance of each element is recorded, at dst, with
+// indexes starting from offset.
+func (m *Mode) elements(list []interface{}, src, dst Path, offset int) []interface{} {
+	result := make([]interface{}, 0, len(list))
+	for i, v := range list {
+		newSrc := append(src, Segment{Value: strconv.Itoa(i), Index: true})
+
+		if !m.includeValue(newSrc, v) {
+			if m.ArrayExclusion == ArrayPreserve {
+				result = append(result, nil)
+			}
+			continue
+		}
+
+		var newDst Path
+		if dst != nil {
+			newDst = appendSegment(dst, Segment{Value: strconv.Itoa(offset + len(result)), Index: true})
+		}
+
+		result = append(result, m.filter(v, newSrc, newDst))
+	}
+	return result
+}
+
+// filter copies v, at src, removing anything that isn't included. If dst is not nil, the provenance of each leaf is
+// recorded, at dst.
+func (m *Mode) filter(v interface{}, src, dst Path) interface{} {
+	var result interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		r := make(map[string]interface{})
+		for k, v := range t {
+			newSrc := append(src, Segment{Value: k})
+
+			if !m.includeValue(newSrc, v) {
+				continue
+			}
+
+			var newDst Path
+			if dst != nil {
+				newDst = appendSegment(dst, Segment{Value: k})
+			}
+
+			r[k] = m.filter(v, newSrc, newDst)
+		}
+		if len(r) != 0 {
+			return r
+		}
+		result = r
+
+	case []interface{}:
+		r := m.elements(t, src, dst, 0)
+		if len(r) != 0 {
+			return r
+		}
+		result = r
+
+	case Elements:
+		result = make([]interface{}, 0)
+
+	default:
+		result = v
+	}
+	if dst != nil {
+		m.record(result, dst, src, nil)
+	}
+	return result
+}
+
+// replace filters b, at src, replacing a, at path.
+func (m *Mode) replace(a, b interface{}, path, src Path) interface{} {
+	overridden := m.drop(a, path)
+	result := m.filter(b, src, path)
+	m.override(result, path, overridden)
+	return result
+}
+
+// merge merges b into a, where path is the location of a (and the result), and src is the location of b, within its
+// source, which may differ, for array elements.
+func (m *Mode) merge(a, b interface{}, path, src Path) (interface{}, error) {
+	if keep, err := m.conflict(a, b, path); err != nil {
+		return nil, err
+	} else if keep {
+		return a, nil
+	}
+
+	if a != nil && kindOf(a) != kindOf(b) {
+		// replaced by a different kind of value
+		overridden := m.drop(a, path)
+		result, err := m.merge(nil, b, path, src)
+		if err != nil {
+			return nil, err
+		}
+		m.override(result, path, overridden)
+		return result, nil
+	}
+
+	switch tB := b.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{})
+		tA, _ := a.(map[string]interface{})
+
+		// the base has already been filtered
+		for k, vA := range tA {
+			result[k] = vA
+		}
+
+		// in order, so any conflicts are found deterministically
+		keys := make([]string, 0, len(tB))
+		for k := range tB {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vB := tB[k]
+			newPath := appendSegment(path, Segment{Value: k})
+			newSrc := appendSegment(src, Segment{Value: k})
+
+			if !m.includeValue(newSrc, vB) {
+				continue
+			}
+
+			if vB == nil && m.MergePatch {
+				if vA, ok := result[k]; ok {
+					m.drop(vA, newPath)
+				}
+				delete(result, k)
+				continue
+			}
+
+			vA, _ := result[k]
+
+			v, err := m.merge(vA, vB, newPath, newSrc)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = v
+		}
+
+		if len(result) == 0 {
+			m.record(result, path, src, nil)
+		}
+
+		return result, nil
+
+	case []interface{}:
+		result, err := m.mergeArray(a, tB, path, src)
+		if err != nil {
+			return nil, err
+		}
+		if len(result) == 0 {
+			m.record(result, path, src, m.drop(a, path))
+		}
+		return result, nil
+
+	case Elements:
+		result, err := m.mergeElements(a, tB, path, src)
+		if err != nil {
+			return nil, err
+		}
+		if len(result) == 0 && a == nil {
+			m.record(result, path, src, nil)
+		}
+		return result, nil
+
+	default:
+		return m.replace(a, b, path, src), nil
+	}
+}
+
+// mergeArray merges b into a, using the strategy for src, see merge. The base has already been filtered, so indexes
+// of the overlay (only) are used to exclude elements.
+func (m *Mode) mergeArray(a interface{}, tB []interface{}, path, src Path) ([]interface{}, error) {
+	tA, _ := a.([]interface{})
+	result := append(make([]interface{}, 0, len(tA)+len(tB)), tA...)
+
+	strategy, key := m.arrayStrategy(src)
+	switch strategy {
+	case ArrayReplace:
+		overridden := m.drop(a, path)
+		if result = m.elements(tB, src, path, 0); len(result) == 0 {
+			m.record(result, path, src, overridden)
+		}
+		m.override(result, path, overridden)
+		return result, nil
+
+	case ArrayAppend:
+		return append(result, m.elements(tB, src, path, len(tA))...), nil
+
+	case ArrayPrepend:
+		n := len(m.elements(tB, src, nil, 0))
+		m.move(tA, path, func(i int) int { return i + n })
+		return append(m.elements(tB, src, path, 0), tA...), nil
+
+	case ArrayUniqueUnion:
+		result = result[:0]
+		contains := func(v interface{}) bool {
+			for _, existing := range result {
+				if reflect.DeepEqual(existing, v) {
+					return true
+				}
+			}
+			return false
+		}
+		positions := make([]int, len(tA))
+		for i, v := range tA {
+			positions[i] = -1
+			if !contains(v) {
+				positions[i] = len(result)
+				result = append(result, v)
+			}
+		}
+		m.move(tA, path, func(i int) int { return positions[i] })
+		for i, vB := range tB {
+			newSrc := append(src, Segment{Value: strconv.Itoa(i), Index: true})
+
+			if !m.includeValue(newSrc, vB) {
+				if m.ArrayExclusion == ArrayPreserve && !contains(nil) {
+					result = append(result, nil)
+				}
+				continue
+			}
+
+			if contains(m.filter(vB, newSrc, nil)) {
+				continue
+			}
+
+			result = append(result, m.filter(vB, newSrc, appendSegment(path, Segment{Value: strconv.Itoa(len(result)), Index: true})))
+		}
+		return result, nil
+
+	case ArrayMergeByKey:
+		for i, vB := range tB {
+			newSrc := appendSegment(src, Segment{Value: strconv.Itoa(i), Index: true})
+
+			if !m.includeValue(newSrc, vB) {
+				continue
+			}
+
+			if k, ok := arrayKey(vB, key); ok {
+				if j := indexOfKey(result, key, k); j >= 0 {
+					v, err := m.merge(result[j], vB, appendSegment(path, Segment{Value: strconv.Itoa(j), Index: true}), newSrc)
+					if err != nil {
+						return nil, err
+					}
+					result[j] = v
+					continue
+				}
+			}
+
+			result = append(result, m.filter(vB, newSrc, appendSegment(path, Segment{Value: strconv.Itoa(len(result)), Index: true})))
+		}
+		return result, nil
+	}
+
+	// elements are matched by position, after removing any excluded elements of the overlay, or, if preserving
+	// positions, the base element is retained (or null, if there isn't one) in place of an excluded element
+	var n int // the position in result
+	for i, vB := range tB {
+		newSrc := append(src, Segment{Value: strconv.Itoa(i), Index: true})
+
+		if !m.includeValue(newSrc, vB) {
+			if m.ArrayExclusion == ArrayPreserve {
+				if n >= len(result) {
+					result = append(result, nil)
+				}
+				n++
+			}
+			continue
+		}
+
+		newPath := appendSegment(path, Segment{Value: strconv.Itoa(n), Index: true})
+		if n < len(result) {
+			result[n] = m.replace(result[n], vB, newPath, newSrc)
+		} else {
+			result = append(result, m.replace(nil, vB, newPath, newSrc))
+		}
+		n++
+	}
+
+	return result, nil
+}
+
+// mergeElements merges each value of b into the element of a at the same index, see Elements.
+func (m *Mode) mergeElements(a interface{}, tB Elements, path, src Path) ([]interface{}, error) {
+	tA, _ := a.([]interface{})
+	result := append(make([]interface{}, 0, len(tA)), tA...)
+
+	// in order, so any conflicts are found deterministically
+	indexes := make([]int, 0, len(tB))
+	for i := range tB {
+		if i >= 0 && i < len(result) {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		vB := tB[i]
+		newPath := appendSegment(path, Segment{Value: strconv.Itoa(i), Index: true})
+		newSrc := appendSegment(src, Segment{Value: strconv.Itoa(i), Index: true})
+
+		if !m.includeValue(newSrc, vB) {
+			continue
+		}
+
+		v, err := m.merge(result[i], vB, newPath, newSrc)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// Merge merges the overlay b into the base a, returning a new value, with anything not included in b removed, or an
+// error if there was a Conflict, and the policy is ConflictError. The base must have already been filtered, i.e. it
+// is the result of Filter, or a previous Merge, and it isn't modified.
+func (m *Mode) Merge(a, b interface{}) (interface{}, error) {
+	return m.merge(a, b, make(Path, 0), make(Path, 0))
+}
+
+// Filter returns a copy of v with anything not included removed, e.g. for the first of many inputs, which
+// (unlike Merge) retains any null values, if MergePatch is enabled. Any previously recorded provenance is reset.
+func (m *Mode) Filter(v interface{}) interface{} {
+	m.provenance = nil
+	return m.filter(v, make(Path, 0), make(Path, 0))
+}
+
+// arrayKey returns the value of the key field, if v is a map containing it.
+func arrayKey(v interface{}, key string) (interface{}, bool) {
+	t, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	k, ok := t[key]
+	return k, ok
+}
+
+// indexOfKey returns the index of the first element of list with a key field equal to k, or -1.
+func indexOfKey(list []interface{}, key string, k interface{}) int {
+	for i, v := range list {
+		if other, ok := arrayKey(v, key); ok && reflect.DeepEqual(k, other) {
+			return i
+		}
+	}
+	return -1
+}
+
+// specificity is the number of segments of pattern without any wildcards.
+func specificity(pattern Path) int {
+	var n int
+	for _, segment := range pattern {
+		if !segment.Pattern {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,875 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-test/deep"
+	"sort"
+	"testing"
+)
+
+type modeTestCase struct {
+	Name       string
+	A, B       string
+	Whitelist  []string
+	Blacklist  []string
+	Arrays     []string
+	MergePatch bool
+	Expected   string
+}
+
+func (c modeTestCase) Run(t *testing.T) {
+	options := Options{
+		Include:    c.Whitelist,
+		Exclude:    c.Blacklist,
+		MergePatch: c.MergePatch,
+	}
+	for _, s := range c.Arrays {
+		rule, err := ParseArrayRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options.Arrays = append(options.Arrays, rule)
+	}
+	mode, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := mode.Filter(parseJSON(t, c.A))
+	if c.B != `` {
+		if data, err = mode.Merge(data, parseJSON(t, c.B)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := deep.Equal(parseJSON(t, c.Expected), data); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestMode_Merge_whitelist(t *testing.T) {
+	for _, testCase := range []modeTestCase{
+		{
+			Name:     `no rules`,
+			A:        `{"a": {"b": 1, "c": [1, 2]}, "d": true}`,
+			B:        `{"a": {"c": [3]}, "e": null}`,
+			Expected: `{"a": {"b": 1, "c": [3, 2]}, "d": true, "e": null}`,
+		},
+		{
+			Name:      `whitelist subtree`,
+			A:         `{"a": {"b": {"x": 1}, "c": 2}, "d": true}`,
+			B:         `{"a": {"b": {"y": 2}}, "e": 3}`,
+			Whitelist: []string{`a.b`},
+			Expected:  `{"a": {"b": {"x": 1, "y": 2}}}`,
+		},
+		{
+			Name:      `multiple whitelists`,
+			A:         `{"a": {"b": 1, "c": 2}, "d": true, "e": "e"}`,
+			Whitelist: []string{`a.c`, `d`},
+			Expected:  `{"a": {"c": 2}, "d": true}`,
+		},
+		{
+			Name:      `blacklist within whitelist`,
+			A:         `{"a": {"b": 1, "secret": 2, "c": {"secret": 3, "d": 4}}, "e": 5}`,
+			Whitelist: []string{`a`},
+			Blacklist: []string{`a.secret`, `a.c.secret`},
+			Expected:  `{"a": {"b": 1, "c": {"d": 4}}}`,
+		},
+		{
+			Name:      `whitelist within blacklist`,
+			A:         `{"a": {"b": {"c": 1, "d": 2}, "e": 3}, "f": 4}`,
+			Whitelist: []string{`a.b.c`},
+			Blacklist: []string{`a`},
+			Expected:  `{"a": {"b": {"c": 1}}}`,
+		},
+		{
+			Name:      `alternating`,
+			A:         `{"a": {"b": {"c": {"d": 1, "e": 2}, "f": 3}, "g": 4}, "h": 5}`,
+			Whitelist: []string{`a`, `a.b.c`},
+			Blacklist: []string{`a.b`, `a.b.c.e`},
+			Expected:  `{"a": {"b": {"c": {"d": 1}}, "g": 4}}`,
+		},
+		{
+			Name:      `whitelist and blacklist the same path`,
+			A:         `{"a": 1, "b": 2}`,
+			Whitelist: []string{`a`},
+			Blacklist: []string{`a`},
+			Expected:  `{"a": 1}`,
+		},
+		{
+			Name:      `blacklist only`,
+			A:         `{"a": {"b": 1, "c": 2}, "d": 3}`,
+			Blacklist: []string{`a.b`, `d`},
+			Expected:  `{"a": {"c": 2}}`,
+		},
+		{
+			Name:      `whitelist missing`,
+			A:         `{"a": {"b": 1}}`,
+			Whitelist: []string{`a.c`, `x`},
+			Expected:  `{}`,
+		},
+		{
+			Name:      `whitelist missing, within an empty object`,
+			A:         `{"a": {}, "b": {"c": 1}}`,
+			B:         `{"b": {"d": 2}}`,
+			Whitelist: []string{`a.c`, `b.c`},
+			Expected:  `{"a": {}, "b": {"c": 1}}`,
+		},
+		{
+			Name:      `whitelist through a scalar`,
+			A:         `{"a": "b", "c": 1}`,
+			B:         `{"c": 2}`,
+			Whitelist: []string{`a.b`, `c`},
+			Expected:  `{"c": 2}`,
+		},
+		{
+			Name:      `whitelist array index`,
+			A:         `{"a": [1, 2, 3], "b": 4}`,
+			Whitelist: []string{`a.1`},
+			Expected:  `{"a": [2]}`,
+		},
+		{
+			Name:      `whitelist array indexes with overlay`,
+			A:         `{"a": [1, 2, 3]}`,
+			B:         `{"a": [10, 20, 30, 40]}`,
+			Whitelist: []string{`a.1`, `a.3`},
+			Expected:  `{"a": [20, 40]}`,
+		},
+		{
+			Name:      `whitelist within array elements`,
+			A:         `{"a": [{"name": "x", "v": 1}, {"name": "y", "v": 2}]}`,
+			Whitelist: []string{`a.0.name`, `a.1.name`},
+			Expected:  `{"a": [{"name": "x"}, {"name": "y"}]}`,
+		},
+		{
+			Name:      `blacklist array element within whitelist`,
+			A:         `{"a": [1, 2, 3], "b": [4]}`,
+			B:         `{"a": [10, 20, 30, 40]}`,
+			Whitelist: []string{`a`},
+			Blacklist: []string{`a.0`},
+			Expected:  `{"a": [20, 30, 40]}`,
+		},
+	} {
+		t.Run(testCase.Name, testCase.Run)
+	}
+}
+
+func TestMode_Merge_patterns(t *testing.T) {
+	for _, testCase := range []modeTestCase{
+		{
+			Name:      `any key`,
+			A:         `{"services": {"api": {"image": "api", "password": "a"}, "db": {"image": "db", "password": "b"}}, "password": "c"}`,
+			Blacklist: []string{`services.*.password`},
+			Expected:  `{"services": {"api": {"image": "api"}, "db": {"image": "db"}}, "password": "c"}`,
+		},
+		{
+			Name:      `any index`,
+			A:         `{"users": [{"name": "a", "password": "a"}, {"name": "b", "password": "b"}]}`,
+			Blacklist: []string{`users.*.password`},
+			Expected:  `{"users": [{"name": "a"}, {"name": "b"}]}`,
+		},
+		{
+			Name:      `any depth`,
+			A:         `{"secret": 1, "a": {"secret": 2, "b": [{"secret": 3, "c": 4}]}, "d": {"not_secret": 5}}`,
+			Blacklist: []string{`**.secret`},
+			Expected:  `{"a": {"b": [{"c": 4}]}, "d": {"not_secret": 5}}`,
+		},
+		{
+			Name:      `any depth in the middle`,
+			A:         `{"a": {"x": {"y": {"b": 1, "c": 2}}, "b": 3}, "b": 4}`,
+			Blacklist: []string{`a.**.b`},
+			Expected:  `{"a": {"x": {"y": {"c": 2}}}, "b": 4}`,
+		},
+		{
+			Name:      `whitelist any depth`,
+			A:         `{"a": {"name": "a", "b": [{"name": "b", "c": 1}]}, "name": "root", "d": 2}`,
+			Whitelist: []string{`**.name`},
+			Expected:  `{"a": {"name": "a", "b": [{"name": "b"}]}, "name": "root"}`,
+		},
+		{
+			Name:      `whitelist wildcard with blacklist`,
+			A:         `{"services": {"api": {"image": "api", "port": 1}, "db": {"image": "db", "port": 2}}, "other": 3}`,
+			Whitelist: []string{`services.*`},
+			Blacklist: []string{`services.db`, `**.port`},
+			Expected:  `{"services": {"api": {"image": "api"}}}`,
+		},
+		{
+			Name:      `character classes`,
+			A:         `{"a1": 1, "a2": 2, "a3": 3, "b1": 4, "list": [0, 1, 2, 3]}`,
+			Blacklist: []string{`a[1-2]`, `[!a]?`, `list.[1-2]`},
+			Expected:  `{"a3": 3, "list": [0, 3]}`,
+		},
+		{
+			Name:      `partial matches`,
+			A:         `{"prefix_a": 1, "a_suffix": 2, "prefix_b_suffix": 3, "other": 4}`,
+			Blacklist: []string{`prefix_*`, `*_suffix`},
+			Expected:  `{"other": 4}`,
+		},
+		{
+			Name:      `escaped`,
+			A:         `{"*": 1, "a": 2, "?": 3}`,
+			Blacklist: []string{`\*`, `\?`},
+			Expected:  `{"a": 2}`,
+		},
+	} {
+		t.Run(testCase.Name, testCase.Run)
+	}
+}
+
+func TestMode_Merge_arrays(t *testing.T) {
+	const (
+		a = `{"x": [1, 2, 3], "y": [{"k": 1}, {"k": 2}], "z": {"x": [1, 2]}}`
+		b = `{"x": [3, 4], "y": [{"k": 2}, {"k": 3}], "z": {"x": [2]}}`
+	)
+	for _, testCase := range []modeTestCase{
+		{
+			Name:     `index-merge by default`,
+			A:        a,
+			B:        b,
+			Expected: `{"x": [3, 4, 3], "y": [{"k": 2}, {"k": 3}], "z": {"x": [2, 2]}}`,
+		},
+		{
+			Name:     `index-merge`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`INDEX-MERGE`},
+			Expected: `{"x": [3, 4, 3], "y": [{"k": 2}, {"k": 3}], "z": {"x": [2, 2]}}`,
+		},
+		{
+			Name:     `replace`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`replace`},
+			Expected: `{"x": [3, 4], "y": [{"k": 2}, {"k": 3}], "z": {"x": [2]}}`,
+		},
+		{
+			Name:     `append`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`append`},
+			Expected: `{"x": [1, 2, 3, 3, 4], "y": [{"k": 1}, {"k": 2}, {"k": 2}, {"k": 3}], "z": {"x": [1, 2, 2]}}`,
+		},
+		{
+			Name:     `prepend`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`prepend`},
+			Expected: `{"x": [3, 4, 1, 2, 3], "y": [{"k": 2}, {"k": 3}, {"k": 1}, {"k": 2}], "z": {"x": [2, 1, 2]}}`,
+		},
+		{
+			Name:     `unique-union`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`unique-union`},
+			Expected: `{"x": [1, 2, 3, 4], "y": [{"k": 1}, {"k": 2}, {"k": 3}], "z": {"x": [1, 2]}}`,
+		},
+		{
+			Name:     `unique-union with duplicates in the base`,
+			A:        `[1, 1, "1", null, 2]`,
+			B:        `[null, 2, 3, 3]`,
+			Arrays:   []string{`unique-union`},
+			Expected: `[1, "1", null, 2, 3]`,
+		},
+		{
+			Name:     `per path`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`x=append`, `y=replace`},
+			Expected: `{"x": [1, 2, 3, 3, 4], "y": [{"k": 2}, {"k": 3}], "z": {"x": [2, 2]}}`,
+		},
+		{
+			Name:     `per path with default`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`prepend`, `x=append`, `z.x=unique-union`},
+			Expected: `{"x": [1, 2, 3, 3, 4], "y": [{"k": 2}, {"k": 3}, {"k": 1}, {"k": 2}], "z": {"x": [1, 2]}}`,
+		},
+		{
+			Name:     `most specific wins`,
+			A:        a,
+			B:        b,
+			Arrays:   []string{`**=replace`, `*.x=prepend`, `z.x=append`},
+			Expected: `{"x": [3, 4], "y": [{"k": 2}, {"k": 3}], "z": {"x": [1, 2, 2]}}`,
+		},
+		{
+			Name:     `nested arrays`,
+			A:        `{"m": [[1], [2]]}`,
+			B:        `{"m": [[3]]}`,
+			Arrays:   []string{`m=append`, `m[*]=replace`},
+			Expected: `{"m": [[1], [2], [3]]}`,
+		},
+		{
+			Name:      `exclusions`,
+			A:         `{"x": [1, 2]}`,
+			B:         `{"x": [4, 5, 6]}`,
+			Arrays:    []string{`append`},
+			Blacklist: []string{`x[2]`},
+			Expected:  `{"x": [1, 2, 4, 5]}`,
+		},
+		{
+			Name:     `keys containing equals`,
+			A:        `{"a=b": [1]}`,
+			B:        `{"a=b": [2]}`,
+			Arrays:   []string{`["a=b"]=append`},
+			Expected: `{"a=b": [1, 2]}`,
+		},
+	} {
+		t.Run(testCase.Name, testCase.Run)
+	}
+
+	for _, s := range []string{`merge`, `x=`, `=append`, `x..y=append`, `merge-by-key`, `x=merge-by-key:`, `x=append:name`} {
+		rule, err := ParseArrayRule(s)
+		if err == nil {
+			_, err = New(Options{Arrays: []ArrayRule{rule}})
+		}
+		if err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestMode_Merge_arraysByKey(t *testing.T) {
+	for _, testCase := range []modeTestCase{
+		{
+			Name:     `matched elements are merged in place`,
+			A:        `[{"name": "a", "v": 1, "x": 1}, {"name": "b", "v": 2}, {"name": "c", "v": 3}]`,
+			B:        `[{"name": "c", "v": 30}, {"name": "a", "v": 10}]`,
+			Arrays:   []string{`merge-by-key:name`},
+			Expected: `[{"name": "a", "v": 10, "x": 1}, {"name": "b", "v": 2}, {"name": "c", "v": 30}]`,
+		},
+		{
+			Name:     `unmatched elements are appended`,
+			A:        `[{"id": 1}, {"id": 2}]`,
+			B:        `[{"id": 3, "v": true}, {"id": 2, "v": false}, {"id": 4}]`,
+			Arrays:   []string{`merge-by-key:id`},
+			Expected: `[{"id": 1}, {"id": 2, "v": false}, {"id": 3, "v": true}, {"id": 4}]`,
+		},
+		{
+			Name:     `elements without the key`,
+			A:        `[{"id": 1}, "a", {"other": 1}]`,
+			B:        `["a", {"other": 1}, {"id": 1, "v": 1}, {"id": "1"}]`,
+			Arrays:   []string{`merge-by-key:id`},
+			Expected: `[{"id": 1, "v": 1}, "a", {"other": 1}, "a", {"other": 1}, {"id": "1"}]`,
+		},
+		{
+			Name:     `nested strategies`,
+			A:        `{"c": [{"name": "a", "env": [{"name": "X", "value": "1"}, {"name": "Y", "value": "2"}], "ports": [1]}]}`,
+			B:        `{"c": [{"name": "a", "env": [{"name": "Y", "value": "3"}, {"name": "Z", "value": "4"}], "ports": [2]}]}`,
+			Arrays:   []string{`c=merge-by-key:name`, `c.*.env=merge-by-key:name`, `c.*.ports=unique-union`},
+			Expected: `{"c": [{"name": "a", "env": [{"name": "X", "value": "1"}, {"name": "Y", "value": "3"}, {"name": "Z", "value": "4"}], "ports": [1, 2]}]}`,
+		},
+		{
+			Name:     `only the configured path`,
+			A:        `{"a": [{"k": 1, "v": 1}], "b": [{"k": 1, "v": 1}]}`,
+			B:        `{"a": [{"k": 1, "v": 2}], "b": [{"k": 2, "v": 2}]}`,
+			Arrays:   []string{`a=merge-by-key:k`},
+			Expected: `{"a": [{"k": 1, "v": 2}], "b": [{"k": 2, "v": 2}]}`,
+		},
+		{
+			Name:      `exclusions`,
+			A:         `[{"k": 1, "secret": 1}, {"k": 2}]`,
+			B:         `[{"k": 2, "secret": 2}, {"k": 3, "secret": 3}]`,
+			Arrays:    []string{`merge-by-key:k`},
+			Blacklist: []string{`*.secret`},
+			Expected:  `[{"k": 1}, {"k": 2}, {"k": 3}]`,
+		},
+	} {
+		t.Run(testCase.Name, testCase.Run)
+	}
+}
+
+func TestMode_Merge_elements(t *testing.T) {
+	for _, testCase := range []struct {
+		Name      string
+		A         string
+		B         interface{}
+		Arrays    []string
+		Blacklist []string
+		Expected  string
+	}{
+		{
+			Name:     `regardless of the strategy`,
+			A:        `{"a": ["a", "b", "c"], "b": ["a", "b"]}`,
+			B:        map[string]interface{}{`a`: Elements{1: `x`, 3: `y`, -1: `z`}, `b`: Elements{0: `b`}},
+			Arrays:   []string{`a=append`, `b=unique-union`},
+			Expected: `{"a": ["a", "x", "c"], "b": ["b", "b"]}`,
+		},
+		{
+			Name:     `objects are merged`,
+			A:        `[{"host": "a", "port": 80, "tags": [1, 2]}, {"host": "b"}]`,
+			B:        Elements{0: map[string]interface{}{`port`: float64(81), `tags`: []interface{}{float64(3)}}},
+			Arrays:   []string{`append`},
+			Expected: `[{"host": "a", "port": 81, "tags": [1, 2, 3]}, {"host": "b"}]`,
+		},
+		{
+			Name:      `exclusions`,
+			A:         `[{"k": 1}, {"k": 2}]`,
+			B:         Elements{0: map[string]interface{}{`secret`: true}, 1: map[string]interface{}{`k`: float64(3)}},
+			Blacklist: []string{`[0]`, `*.secret`},
+			Expected:  `[{"k": 2}]`,
+		},
+		{
+			Name:     `not an array`,
+			A:        `{"a": {"b": 1}, "c": 1}`,
+			B:        map[string]interface{}{`a`: Elements{0: `x`}, `d`: Elements{0: `y`}},
+			Expected: `{"a": [], "c": 1, "d": []}`,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			options := Options{Exclude: testCase.Blacklist}
+			for _, s := range testCase.Arrays {
+				rule, err := ParseArrayRule(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				options.Arrays = append(options.Arrays, rule)
+			}
+			mode, err := New(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := mode.Merge(mode.Filter(parseJSON(t, testCase.A)), testCase.B)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Expected), data); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+
+	mode := NewMode()
+	if diff := deep.Equal([]interface{}{}, mode.Filter(Elements{0: `x`})); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestMode_Merge_mergePatch(t *testing.T) {
+	// test cases from RFC 7386 Appendix A
+	for _, testCase := range []modeTestCase{
+		{A: `{"a":"b"}`, B: `{"a":"c"}`, Expected: `{"a":"c"}`},
+		{A: `{"a":"b"}`, B: `{"b":"c"}`, Expected: `{"a":"b","b":"c"}`},
+		{A: `{"a":"b"}`, B: `{"a":null}`, Expected: `{}`},
+		{A: `{"a":"b","b":"c"}`, B: `{"a":null}`, Expected: `{"b":"c"}`},
+		{A: `{"a":["b"]}`, B: `{"a":"c"}`, Expected: `{"a":"c"}`},
+		{A: `{"a":"c"}`, B: `{"a":["b"]}`, Expected: `{"a":["b"]}`},
+		{A: `{"a":{"b":"c"}}`, B: `{"a":{"b":"d","c":null}}`, Expected: `{"a":{"b":"d"}}`},
+		{A: `{"a":[{"b":"c"}]}`, B: `{"a":[1]}`, Expected: `{"a":[1]}`},
+		{A: `["a","b"]`, B: `["c","d"]`, Expected: `["c","d"]`},
+		{A: `{"a":"b"}`, B: `["c"]`, Expected: `["c"]`},
+		{A: `{"a":"foo"}`, B: `null`, Expected: `null`},
+		{A: `{"a":"foo"}`, B: `"bar"`, Expected: `"bar"`},
+		{A: `{"e":null}`, B: `{"a":1}`, Expected: `{"e":null,"a":1}`},
+		{A: `[1,2]`, B: `{"a":"b","c":null}`, Expected: `{"a":"b"}`},
+		{A: `{}`, B: `{"a":{"bb":{"ccc":null}}}`, Expected: `{"a":{"bb":{}}}`},
+	} {
+		testCase.Name = testCase.A + ` ` + testCase.B
+		testCase.MergePatch = true
+		t.Run(testCase.Name, testCase.Run)
+	}
+
+	for _, testCase := range []modeTestCase{
+		{
+			Name:       `arrays within arrays are retained`,
+			A:          `{"a": 1}`,
+			B:          `{"b": [{"c": null}]}`,
+			MergePatch: true,
+			Expected:   `{"a": 1, "b": [{"c": null}]}`,
+		},
+		{
+			Name:       `array strategies`,
+			A:          `{"a": [1], "b": [1]}`,
+			B:          `{"a": [2], "b": [2]}`,
+			MergePatch: true,
+			Arrays:     []string{`a=append`},
+			Expected:   `{"a": [1, 2], "b": [2]}`,
+		},
+		{
+			Name:       `default array strategy`,
+			A:          `{"a": [1, 2], "b": [1]}`,
+			B:          `{"a": [3], "b": [2]}`,
+			MergePatch: true,
+			Arrays:     []string{`index-merge`},
+			Expected:   `{"a": [3, 2], "b": [2]}`,
+		},
+		{
+			Name:       `exclusions`,
+			A:          `{"a": 1, "b": 2}`,
+			B:          `{"a": null, "b": null}`,
+			MergePatch: true,
+			Blacklist:  []string{`b`},
+			Expected:   `{}`,
+		},
+		{
+			Name:     `null without merge patch`,
+			A:        `{"a": 1}`,
+			B:        `{"a": null}`,
+			Expected: `{"a": null}`,
+		},
+	} {
+		t.Run(testCase.Name, testCase.Run)
+	}
+}
+
+func TestMode_Merge_arrayExclusions(t *testing.T) {
+	const max = 4
+
+	// filter models an input, given the indexes to exclude
+	filter := func(prefix string, n int, excluded []bool, exclusion ArrayExclusion) []interface{} {
+		result := make([]interface{}, 0)
+		for i := 0; i < n; i++ {
+			if !excluded[i] {
+				result = append(result, fmt.Sprintf("%s%d", prefix, i))
+			} else if exclusion == ArrayPreserve {
+				result = append(result, nil)
+			}
+		}
+		return result
+	}
+
+	// merge models each strategy, given the filtered inputs
+	merge := func(strategy ArrayStrategy, exclusion ArrayExclusion, a, b []interface{}, excluded []bool) []interface{} {
+		switch strategy {
+		case ArrayReplace:
+			return b
+		case ArrayAppend:
+			return append(append([]interface{}{}, a...), b...)
+		case ArrayPrepend:
+			return append(append([]interface{}{}, b...), a...)
+		case ArrayUniqueUnion:
+			result := make([]interface{}, 0)
+			seen := make(map[interface{}]bool)
+			for _, v := range append(append([]interface{}{}, a...), b...) {
+				if !seen[v] {
+					seen[v] = true
+					result = append(result, v)
+				}
+			}
+			return result
+		}
+		result := append([]interface{}{}, a...)
+		for i, v := range b {
+			if exclusion == ArrayPreserve && excluded[i] && i < len(result) {
+				continue
+			}
+			if i < len(result) {
+				result[i] = v
+			} else {
+				result = append(result, v)
+			}
+		}
+		return result
+	}
+
+	format := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	var count int
+	for _, strategy := range []ArrayStrategy{ArrayIndexMerge, ArrayReplace, ArrayAppend, ArrayPrepend, ArrayUniqueUnion} {
+		for _, exclusion := range []ArrayExclusion{ArrayCompact, ArrayPreserve} {
+			for whitelist := 0; whitelist < 2; whitelist++ {
+				for nA := 0; nA <= max; nA++ {
+					for nB := 0; nB <= max; nB++ {
+						for mask := 0; mask < 1<<max; mask++ {
+							if whitelist != 0 && mask == 1<<max-1 {
+								// nothing whitelisted
+								continue
+							}
+							excluded := make([]bool, max)
+							mode := NewMode()
+							mode.Array = strategy
+							mode.ArrayExclusion = exclusion
+							for i := range excluded {
+								excluded[i] = mask&(1<<i) != 0
+								if excluded[i] == (whitelist == 0) {
+									node, err := mode.Define(fmt.Sprintf("x[%d]", i))
+									if err != nil {
+										t.Fatal(err)
+									}
+									node.Whitelist = whitelist != 0
+									node.Blacklist = whitelist == 0
+								}
+							}
+
+							a := filter(`a`, nA, excluded, exclusion)
+							b := filter(`b`, nB, excluded, exclusion)
+							// a partially whitelisted array is removed, if nothing within it is, unless it was empty
+							hasA := whitelist == 0 || nA == 0 || len(a) != 0
+							hasB := whitelist == 0 || nB == 0 || len(b) != 0
+							expected := make(map[string]interface{})
+							switch {
+							case hasA && hasB:
+								expected[`x`] = merge(strategy, exclusion, a, b, excluded)
+							case hasA:
+								expected[`x`] = a
+							case hasB:
+								expected[`x`] = merge(strategy, exclusion, make([]interface{}, 0), b, excluded)
+							}
+
+							input := func(prefix string, n int) map[string]interface{} {
+								list := make([]interface{}, n)
+								for i := range list {
+									list[i] = fmt.Sprintf("%s%d", prefix, i)
+								}
+								return map[string]interface{}{`x`: list}
+							}
+
+							data, err := mode.Merge(mode.Filter(input(`a`, nA)), input(`b`, nB))
+							if err != nil {
+								t.Fatal(err)
+							}
+							if diff := deep.Equal(expected, data); diff != nil {
+								t.Errorf("%s %s whitelist=%d base=%d overlay=%d excluded=%04b: expected %s got %s",
+									strategy, exclusion, whitelist, nA, nB, mask, format(expected), format(data))
+							}
+							count++
+						}
+					}
+				}
+			}
+		}
+	}
+	if count != 5*2*(max+1)*(max+1)*(1<<max+1<<max-1) {
+		t.Error(count)
+	}
+}
+
+func TestMode_Merge_arrayExclusionsMany(t *testing.T) {
+	for _, testCase := range []struct {
+		Name      string
+		Exclusion ArrayExclusion
+		Strategy  ArrayStrategy
+		Inputs    []string
+		Expected  string
+	}{
+		{
+			Name:     `compact`,
+			Inputs:   []string{`["a0", "a1", "a2"]`, `["b0"]`, `["c0", "c1", "c2", "c3"]`},
+			Expected: `["c0", "c2", "c3"]`,
+		},
+		{
+			Name:     `compact shorter overlays`,
+			Inputs:   []string{`["a0", "a1", "a2"]`, `["b0"]`, `["c0"]`},
+			Expected: `["c0", "a2"]`,
+		},
+		{
+			Name:      `preserve`,
+			Exclusion: ArrayPreserve,
+			Inputs:    []string{`["a0", "a1", "a2"]`, `["b0"]`, `["c0", "c1", "c2", "c3"]`},
+			Expected:  `["c0", null, "c2", "c3"]`,
+		},
+		{
+			Name:      `preserve shorter overlays`,
+			Exclusion: ArrayPreserve,
+			Inputs:    []string{`["a0"]`, `["b0", "b1"]`, `["c0", "c1", "c2"]`},
+			Expected:  `["c0", null, "c2"]`,
+		},
+		{
+			Name:     `append`,
+			Strategy: ArrayAppend,
+			Inputs:   []string{`["a0", "a1", "a2"]`, `["b0", "b1"]`, `["c0", "c1"]`},
+			Expected: `["a0", "a2", "b0", "c0"]`,
+		},
+		{
+			Name:      `append preserve`,
+			Strategy:  ArrayAppend,
+			Exclusion: ArrayPreserve,
+			Inputs:    []string{`["a0", "a1", "a2"]`, `["b0", "b1"]`, `["c0", "c1"]`},
+			Expected:  `["a0", null, "a2", "b0", null, "c0", null]`,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			mode := NewMode()
+			mode.Array = testCase.Strategy
+			mode.ArrayExclusion = testCase.Exclusion
+			node, err := mode.Define(`[1]`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node.Blacklist = true
+			var data interface{}
+			for i, input := range testCase.Inputs {
+				if i == 0 {
+					data = mode.Filter(parseJSON(t, input))
+				} else if data, err = mode.Merge(data, parseJSON(t, input)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Expected), data); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMode_Merge_conflicts(t *testing.T) {
+	for _, testCase := range []struct {
+		Name        string
+		A, B        string
+		Policy      ConflictPolicy
+		Expected    string
+		Conflicts   []string
+		Error       string
+		MergePatch  bool
+		ArrayPolicy string
+	}{
+		{
+			Name:      `overwrite object with scalar`,
+			A:         `{"db":{"host":"x"},"a":1}`,
+			B:         `{"db":"postgres://y"}`,
+			Expected:  `{"db":"postgres://y","a":1}`,
+			Conflicts: []string{`conflict at db: replaced object (from a.json) with scalar (from b.json)`},
+		},
+		{
+			Name:      `overwrite array with object`,
+			A:         `{"hosts":["a"]}`,
+			B:         `{"hosts":{"a":1}}`,
+			Expected:  `{"hosts":{"a":1}}`,
+			Conflicts: []string{`conflict at hosts: replaced array (from a.json) with object (from b.json)`},
+		},
+		{
+			Name:      `keep base`,
+			A:         `{"db":{"host":"x"},"hosts":["a"],"a":1}`,
+			B:         `{"db":"postgres://y","hosts":{"a":1},"a":2}`,
+			Policy:    ConflictKeepBase,
+			Expected:  `{"db":{"host":"x"},"hosts":["a"],"a":2}`,
+			Conflicts: []string{`conflict at db: kept object (from a.json), ignoring scalar (from b.json)`, `conflict at hosts: kept array (from a.json), ignoring object (from b.json)`},
+		},
+		{
+			Name:     `error`,
+			A:        `{"db":{"host":"x"}}`,
+			B:        `{"db":{"host":["y"]}}`,
+			Policy:   ConflictError,
+			Error:    `conflict at db.host: scalar (from a.json) and array (from b.json)`,
+			Expected: `null`,
+		},
+		{
+			Name:     `keep base root`,
+			A:        `{"a":1}`,
+			B:        `[1]`,
+			Policy:   ConflictKeepBase,
+			Expected: `{"a":1}`,
+			Conflicts: []string{
+				`conflict at the root: kept object (from a.json), ignoring array (from b.json)`,
+			},
+		},
+		{
+			Name:     `null never conflicts`,
+			A:        `{"a":null,"b":{"c":1}}`,
+			B:        `{"a":{"b":1},"b":null}`,
+			Policy:   ConflictError,
+			Expected: `{"a":{"b":1},"b":null}`,
+		},
+		{
+			Name:     `scalar types never conflict`,
+			A:        `{"a":1,"b":"x"}`,
+			B:        `{"a":"1","b":true}`,
+			Policy:   ConflictError,
+			Expected: `{"a":"1","b":true}`,
+		},
+		{
+			Name:       `merge patch removal`,
+			A:          `{"a":{"b":1}}`,
+			B:          `{"a":null}`,
+			Policy:     ConflictError,
+			MergePatch: true,
+			Expected:   `{}`,
+		},
+		{
+			Name:        `merge by key elements`,
+			A:           `{"c":[{"name":"x","env":{"A":"1"}}]}`,
+			B:           `{"c":[{"name":"x","env":["A=2"]}]}`,
+			Policy:      ConflictError,
+			ArrayPolicy: `c=merge-by-key:name`,
+			Error:       `conflict at c[0].env: object (from a.json) and array (from b.json)`,
+			Expected:    `null`,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			mode := NewMode()
+			mode.Conflict = testCase.Policy
+			mode.MergePatch = testCase.MergePatch
+			if testCase.ArrayPolicy != `` {
+				defineArrayRules(t, mode, testCase.ArrayPolicy)
+			}
+			mode.Source = `a.json`
+			data := mode.Filter(parseJSON(t, testCase.A))
+			mode.Source = `b.json`
+			data, err := mode.Merge(data, parseJSON(t, testCase.B))
+			if testCase.Error != `` {
+				if err == nil || err.Error() != testCase.Error {
+					t.Errorf("expected error %q, got %v", testCase.Error, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Expected), data); diff != nil {
+				t.Error(diff)
+			}
+			if testCase.Error != `` {
+				return
+			}
+			warnings := make([]string, 0)
+			for _, conflict := range mode.Conflicts {
+				warnings = append(warnings, conflict.Warning())
+			}
+			sort.Strings(warnings)
+			expected := append([]string{}, testCase.Conflicts...)
+			if diff := deep.Equal(expected, warnings); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMode_Merge_conflictSources(t *testing.T) {
+	mode := NewMode()
+	mode.Conflict = ConflictError
+	mode.Source = `a`
+	data := mode.Filter(parseJSON(t, `{"x":{"y":{"z":1}}}`))
+	mode.Source = `b`
+	data, err := mode.Merge(data, parseJSON(t, `{"x":{"y":{"w":2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode.Source = `c`
+	data, err = mode.Merge(data, parseJSON(t, `{"x":{"y":{"w":{}}}}`))
+	if err == nil || err.Error() != `conflict at x.y.w: scalar (from b) and object (from c)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = mode.Merge(parseJSON(t, `{"x":{"y":{"z":1,"w":2}}}`), parseJSON(t, `{"x":{"y":{"z":[]}}}`))
+	if err == nil || err.Error() != `conflict at x.y.z: scalar (from a) and array (from c)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func defineArrayRules(t *testing.T, mode *Mode, specs ...string) {
+	t.Helper()
+	for _, s := range specs {
+		rule, err := ParseArrayRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mode.defineArrayRule(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func parsePath(t *testing.T, s string) Path {
+	t.Helper()
+	path, err := ParsePath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseJSON(t *testing.T, s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options configures a Mode, see New.
+type Options struct {
+	// Include are whitelisted paths, if any are provided, only they (and the objects or arrays containing them) will
+	// be included.
+	Include []string
+	// Exclude are blacklisted paths, which will be excluded, unless a more specific path is included.
+	Exclude []string
+	// Arrays are the strategies for merging arrays, where a rule without a path sets the default, see ParseArrayRule.
+	Arrays []ArrayRule
+	// ArrayExclusion controls whether excluded array elements are removed, or replaced with null.
+	ArrayExclusion ArrayExclusion
+	// MergePatch enables JSON Merge Patch (RFC 7386) semantics, see Mode.
+	MergePatch bool
+	// Conflict is the policy for changes in the kind of value at a path.
+	Conflict ConflictPolicy
+}
+
+// ArrayRule is the strategy for merging arrays at a path.
+type ArrayRule struct {
+	// Path is a path, which may contain wildcards, or empty, for the default strategy.
+	Path     string
+	Strategy ArrayStrategy
+	// Key is the key field used by ArrayMergeByKey, which is required by (and only supported by) it.
+	Key string
+}
+
+// New returns a Mode configured using options, or an error if any are invalid.
+func New(options Options) (*Mode, error) {
+	mode := NewMode()
+	mode.ArrayExclusion = options.ArrayExclusion
+	mode.MergePatch = options.MergePatch
+	mode.Conflict = options.Conflict
+	for _, s := range options.Include {
+		node, err := mode.Define(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include path: %s", err.Error())
+		}
+		node.Whitelist = true
+	}
+	for _, s := range options.Exclude {
+		node, err := mode.Define(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude path: %s", err.Error())
+		}
+		node.Blacklist = true
+	}
+	for _, rule := range options.Arrays {
+		if err := mode.defineArrayRule(rule); err != nil {
+			return nil, fmt.Errorf("invalid array rule for '%s': %s", rule.Path, err.Error())
+		}
+	}
+	return mode, nil
+}
+
+// ParseArrayRule parses an array rule, either STRATEGY or PATH=STRATEGY, where the merge-by-key strategy is followed
+// by the key field, e.g. containers=merge-by-key:name.
+func ParseArrayRule(s string) (ArrayRule, error) {
+	i := strings.LastIndex(s, "=")
+	name, key := s[i+1:], ""
+	if j := strings.Index(name, ":"); j >= 0 {
+		name, key = name[:j], name[j+1:]
+	}
+	strategy, err := ParseArrayStrategy(name)
+	if err != nil {
+		return ArrayRule{}, err
+	}
+	rule := ArrayRule{Strategy: strategy, Key: key}
+	if i >= 0 {
+		if rule.Path = s[:i]; rule.Path == "" {
+			return ArrayRule{}, fmt.Errorf("invalid path: empty")
+		}
+	}
+	if err := rule.validate(); err != nil {
+		return ArrayRule{}, err
+	}
+	return rule, nil
+}
+
+func (r ArrayRule) validate() error {
+	if r.Strategy == ArrayDefault {
+		return fmt.Errorf("invalid strategy: %s", r.Strategy)
+	}
+	if r.Strategy == ArrayMergeByKey && r.Key == "" {
+		return fmt.Errorf("%s requires a key field, e.g. %s:name", r.Strategy, r.Strategy)
+	}
+	if r.Strategy != ArrayMergeByKey && r.Key != "" {
+		return fmt.Errorf("%s doesn't support a key field", r.Strategy)
+	}
+	return nil
+}
+
+func (m *Mode) defineArrayRule(rule ArrayRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	if rule.Path == "" {
+		m.Array = rule.Strategy
+		m.ArrayKey = rule.Key
+		return nil
+	}
+	node, err := m.Define(rule.Path)
+	if err != nil {
+		return err
+	}
+	node.Array = rule.Strategy
+	node.ArrayKey = rule.Key
+	return nil
+}
@@ -0,0 +1,41 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestParseArrayRule(t *testing.T) {
+	for _, testCase := range []struct {
+		Input    string
+		Expected ArrayRule
+	}{
+		{`append`, ArrayRule{Strategy: ArrayAppend}},
+		{`a.b=prepend`, ArrayRule{Path: `a.b`, Strategy: ArrayPrepend}},
+		{`a[*].c=merge-by-key:name`, ArrayRule{Path: `a[*].c`, Strategy: ArrayMergeByKey, Key: `name`}},
+		{`["a=b"]=replace`, ArrayRule{Path: `["a=b"]`, Strategy: ArrayReplace}},
+	} {
+		rule, err := ParseArrayRule(testCase.Input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", testCase.Input, err)
+			continue
+		}
+		if diff := deep.Equal(testCase.Expected, rule); diff != nil {
+			t.Errorf("%q: %v", testCase.Input, diff)
+		}
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	for _, options := range []Options{
+		{Include: []string{`a..b`}},
+		{Exclude: []string{`a[`}},
+		{Arrays: []ArrayRule{{Strategy: ArrayDefault}}},
+		{Arrays: []ArrayRule{{Path: `a`, Strategy: ArrayMergeByKey}}},
+		{Arrays: []ArrayRule{{Path: `a`, Strategy: ArrayAppend, Key: `name`}}},
+	} {
+		if _, err := New(options); err == nil {
+			t.Errorf("%+v: expected an error", options)
+		}
+	}
+}
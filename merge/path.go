@@ -0,0 +1,218 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is a parsed path, as accepted by any option taking one, e.g. `a.b[0]["c.d"]`.
+//
+// Syntax:
+//
+//	path:      [ segment { '.' segment | subscript } ] | subscript { '.' segment | subscript }
+//	segment:   { character | '\' any character }
+//	subscript: '[' ( digits | '*' | quoted ) ']'
+//	quoted:    '"' { character | '\' any character } '"' | "'" { character | '\' any character } "'"
+//
+// Unquoted segments may use the glob-style syntax described by matchSegment, and are literal if they contain no
+// (unescaped) wildcards, noting that a '[' is only treated as a subscript if it contains a number, '*', or a quoted
+// string. Quoted segments are always literal, and may contain any character, including dots.
+type Path []Segment
+
+// Segment is a single key or index of a Path.
+type Segment struct {
+	// Value is the (unescaped) key or index, or, if Pattern is set, a valid pattern, see matchSegment.
+	Value string
+	// Pattern indicates that Value contains wildcards, where "**" matches any number of segments.
+	Pattern bool
+	// Index indicates that the segment used subscript notation, e.g. [0], rather than a key, e.g. .0
+	Index bool
+}
+
+// ParsePath parses s using the path syntax, where an empty string is the root path.
+func ParsePath(s string) (Path, error) {
+	var (
+		result = make(Path, 0)
+		r      = []rune(s)
+		i      int
+	)
+	fail := func(format string, args ...interface{}) (Path, error) {
+		return nil, fmt.Errorf("invalid path '%s' at %d: %s", s, i, fmt.Sprintf(format, args...))
+	}
+
+	for i < len(r) {
+		// subscripts, which may follow anything (if at the start, or after another segment)
+		if n, ok := subscriptLength(r[i:]); ok {
+			segment, err := parseSubscript(r[i : i+n])
+			if err != nil {
+				return fail(err.Error())
+			}
+			result = append(result, segment)
+			i += n
+			continue
+		}
+
+		if len(result) != 0 {
+			if r[i] != '.' {
+				return fail("expected '.' or '['")
+			}
+			i++
+		}
+
+		// an unquoted segment, up to the next dot or subscript
+		var (
+			raw     strings.Builder
+			literal strings.Builder
+			pattern bool
+			start   = i
+		)
+		for i < len(r) && r[i] != '.' {
+			if _, ok := subscriptLength(r[i:]); ok {
+				break
+			}
+			switch r[i] {
+			case '\\':
+				if i+1 >= len(r) {
+					return fail("trailing escape")
+				}
+				raw.WriteRune(r[i])
+				i++
+			case '*', '?', '[':
+				pattern = true
+			}
+			raw.WriteRune(r[i])
+			literal.WriteRune(r[i])
+			i++
+		}
+		if i == start {
+			return fail("empty segment")
+		}
+
+		segment := Segment{Value: literal.String()}
+		if pattern {
+			segment = Segment{Value: raw.String(), Pattern: true}
+			if err := validateSegment(segment.Value); err != nil {
+				return fail("%s in %q", err.Error(), segment.Value)
+			}
+		}
+		result = append(result, segment)
+	}
+
+	if i != 0 && r[i-1] == '.' {
+		return fail("empty segment")
+	}
+
+	return result, nil
+}
+
+// Literal returns true if every segment of the path is literal, i.e. it may only match a single path.
+func (p Path) Literal() bool {
+	for _, segment := range p {
+		if segment.Pattern {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the path using the path syntax, such that it may be parsed again.
+func (p Path) String() string {
+	var b strings.Builder
+	for i, segment := range p {
+		switch {
+		case segment.Pattern && segment.Index:
+			b.WriteString("[" + segment.Value + "]")
+		case segment.Pattern:
+			if i != 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(segment.Value)
+		case segment.Index && isIndex(segment.Value):
+			b.WriteString("[" + segment.Value + "]")
+		case segment.Value != "" && !strings.ContainsAny(segment.Value, `.[]\*?"'`):
+			if i != 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(segment.Value)
+		default:
+			b.WriteString(`["` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(segment.Value) + `"]`)
+		}
+	}
+	return b.String()
+}
+
+// Strings returns the values of each segment.
+func (p Path) Strings() []string {
+	result := make([]string, len(p))
+	for i, segment := range p {
+		result[i] = segment.Value
+	}
+	return result
+}
+
+// subscriptLength returns the length of the subscript at the start of r, if there is one.
+func subscriptLength(r []rune) (int, bool) {
+	if len(r) < 3 || r[0] != '[' {
+		return 0, false
+	}
+	switch r[1] {
+	case '"', '\'':
+		for i := 2; i < len(r); i++ {
+			switch r[i] {
+			case '\\':
+				i++
+			case r[1]:
+				if i+1 < len(r) && r[i+1] == ']' {
+					return i + 2, true
+				}
+				return 0, false
+			}
+		}
+		return 0, false
+	case '*':
+		return 3, r[2] == ']'
+	}
+	for i := 1; i < len(r); i++ {
+		if r[i] == ']' {
+			return i + 1, i > 1
+		}
+		if r[i] < '0' || r[i] > '9' {
+			break
+		}
+	}
+	return 0, false
+}
+
+func parseSubscript(r []rune) (Segment, error) {
+	inner := r[1 : len(r)-1]
+	switch inner[0] {
+	case '*':
+		return Segment{Value: "*", Pattern: true, Index: true}, nil
+	case '"', '\'':
+		var b strings.Builder
+		for i := 1; i < len(inner)-1; i++ {
+			if inner[i] == '\\' {
+				i++
+			}
+			b.WriteRune(inner[i])
+		}
+		return Segment{Value: b.String()}, nil
+	}
+	if !isIndex(string(inner)) {
+		return Segment{}, fmt.Errorf("invalid index %q", string(inner))
+	}
+	return Segment{Value: string(inner), Index: true}, nil
+}
+
+// isIndex returns true if s is a valid (canonical) array index.
+func isIndex(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
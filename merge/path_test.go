@@ -0,0 +1,55 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, testCase := range []struct {
+		S        string
+		Expected Path
+		String   string
+	}{
+		{``, Path{}, ``},
+		{`a`, Path{{Value: `a`}}, `a`},
+		{`a.b.0`, Path{{Value: `a`}, {Value: `b`}, {Value: `0`}}, `a.b.0`},
+		{`a[0][12].b`, Path{{Value: `a`}, {Value: `0`, Index: true}, {Value: `12`, Index: true}, {Value: `b`}}, `a[0][12].b`},
+		{`[0].a`, Path{{Value: `0`, Index: true}, {Value: `a`}}, `[0].a`},
+		{`a\.b.c`, Path{{Value: `a.b`}, {Value: `c`}}, `["a.b"].c`},
+		{`a["b.c"][0]`, Path{{Value: `a`}, {Value: `b.c`}, {Value: `0`, Index: true}}, `a["b.c"][0]`},
+		{`a['b"c']`, Path{{Value: `a`}, {Value: `b"c`}}, `a["b\"c"]`},
+		{`a["b\"\\c"]`, Path{{Value: `a`}, {Value: `b"\c`}}, `a["b\"\\c"]`},
+		{`["*"].b`, Path{{Value: `*`}, {Value: `b`}}, `["*"].b`},
+		{`a[""]`, Path{{Value: `a`}, {Value: ``}}, `a[""]`},
+		{`k8s\.io/name`, Path{{Value: `k8s.io/name`}}, `["k8s.io/name"]`},
+		{`a.*.b`, Path{{Value: `a`}, {Value: `*`, Pattern: true}, {Value: `b`}}, `a.*.b`},
+		{`a[*]`, Path{{Value: `a`}, {Value: `*`, Pattern: true, Index: true}}, `a[*]`},
+		{`**.b?`, Path{{Value: `**`, Pattern: true}, {Value: `b?`, Pattern: true}}, `**.b?`},
+		{`a[!0-9]`, Path{{Value: `a[!0-9]`, Pattern: true}}, `a[!0-9]`},
+		{`a\*b*`, Path{{Value: `a\*b*`, Pattern: true}}, `a\*b*`},
+		{`a\*b`, Path{{Value: `a*b`}}, `["a*b"]`},
+	} {
+		p, err := ParsePath(testCase.S)
+		if err != nil {
+			t.Errorf("%q: %v", testCase.S, err)
+			continue
+		}
+		if diff := deep.Equal(testCase.Expected, p); diff != nil {
+			t.Errorf("%q: %v", testCase.S, diff)
+		}
+		if s := p.String(); s != testCase.String {
+			t.Errorf("%q: expected string %q got %q", testCase.S, testCase.String, s)
+		} else if p2, err := ParsePath(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		} else if diff := deep.Equal(p, p2); diff != nil {
+			t.Errorf("%q: round trip: %v", s, diff)
+		}
+	}
+
+	for _, s := range []string{`.`, `.a`, `a.`, `a..b`, `a\`, `a[0]b`, `a[01]`, `a[b`, `a['b`, `a["b"`, `a.[`} {
+		if _, err := ParsePath(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
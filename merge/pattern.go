@@ -0,0 +1,203 @@
+package merge
+
+import (
+	"errors"
+)
+
+// Unquoted segments of a Path may use glob-style patterns, where "*" matches any (single) key or index, "**" matches
+// any number of segments (including none), and segments are otherwise matched using the syntax below.
+//
+//	'*'         matches any sequence of characters
+//	'?'         matches any single character
+//	'[' [ '!' | '^' ] { character-range } ']'
+//	            character class (must be non-empty)
+//	'\' c       matches character c
+//
+//	character-range:
+//	  c           matches character c (c != '\\', '-', ']')
+//	  '\' c       matches character c
+//	  lo '-' hi   matches character c for lo <= c <= hi
+
+var errBadPattern = errors.New("syntax error in pattern")
+
+// matchPath returns true if path (which should contain no patterns) is matched by pattern.
+func matchPath(pattern, path Path) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if isRecursive(pattern[0]) {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || !pattern[0].Match(path[0]) {
+		return false
+	}
+	return matchPath(pattern[1:], path[1:])
+}
+
+// matchDescendant returns true if pattern may match any path nested within path.
+func matchDescendant(pattern, path Path) bool {
+	if len(path) == 0 {
+		return len(pattern) != 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if isRecursive(pattern[0]) {
+		return matchDescendant(pattern[1:], path) || matchDescendant(pattern, path[1:])
+	}
+	if !pattern[0].Match(path[0]) {
+		return false
+	}
+	return matchDescendant(pattern[1:], path[1:])
+}
+
+func isRecursive(segment Segment) bool {
+	return segment.Pattern && segment.Value == "**"
+}
+
+// Match returns true if the segment (of a pattern) matches the value of other, which should not be a pattern.
+func (s Segment) Match(other Segment) bool {
+	if !s.Pattern {
+		return s.Value == other.Value
+	}
+	return matchSegment(s.Value, other.Value)
+}
+
+// validateSegment returns an error if the pattern is malformed.
+func validateSegment(pattern string) error {
+	p := []rune(pattern)
+	for len(p) != 0 {
+		switch p[0] {
+		case '\\':
+			if len(p) == 1 {
+				return errBadPattern
+			}
+			p = p[2:]
+		case '[':
+			n, ok := classLength(p)
+			if !ok {
+				return errBadPattern
+			}
+			p = p[n:]
+		default:
+			p = p[1:]
+		}
+	}
+	return nil
+}
+
+// matchSegment matches a single (valid) segment of a pattern against a key or index.
+func matchSegment(pattern, s string) bool {
+	return matchRunes([]rune(pattern), []rune(s))
+}
+
+func matchRunes(p, s []rune) bool {
+	for len(p) != 0 {
+		switch p[0] {
+		case '*':
+			for len(p) != 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchRunes(p, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			n, _ := classLength(p)
+			if len(s) == 0 || !matchClass(p[1:n-1], s[0]) {
+				return false
+			}
+			p, s = p[n:], s[1:]
+			continue
+		case '\\':
+			p = p[1:]
+			fallthrough
+		default:
+			if len(s) == 0 || p[0] != s[0] {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// classLength returns the length of the character class at the start of p, including the brackets.
+func classLength(p []rune) (int, bool) {
+	i := 1
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		i++
+	}
+	char := func() bool {
+		if i >= len(p) || p[i] == '-' || p[i] == ']' {
+			return false
+		}
+		if p[i] == '\\' {
+			i++
+			if i >= len(p) {
+				return false
+			}
+		}
+		i++
+		return true
+	}
+	for empty := true; ; empty = false {
+		if i >= len(p) {
+			return 0, false
+		}
+		if p[i] == ']' {
+			return i + 1, !empty
+		}
+		if !char() {
+			return 0, false
+		}
+		if i < len(p) && p[i] == '-' {
+			i++
+			if !char() {
+				return 0, false
+			}
+		}
+	}
+}
+
+// matchClass matches r against the contents of a valid character class.
+func matchClass(class []rune, r rune) bool {
+	negated := len(class) != 0 && (class[0] == '!' || class[0] == '^')
+	if negated {
+		class = class[1:]
+	}
+	next := func() rune {
+		if class[0] == '\\' {
+			class = class[1:]
+		}
+		c := class[0]
+		class = class[1:]
+		return c
+	}
+	for len(class) != 0 {
+		lo := next()
+		hi := lo
+		if len(class) != 0 && class[0] == '-' {
+			class = class[1:]
+			hi = next()
+		}
+		if lo <= r && r <= hi {
+			return !negated
+		}
+	}
+	return negated
+}
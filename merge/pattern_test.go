@@ -0,0 +1,41 @@
+package merge
+
+import "testing"
+
+func TestMatchSegment(t *testing.T) {
+	for _, testCase := range []struct {
+		Pattern, S string
+		Match      bool
+	}{
+		{`abc`, `abc`, true},
+		{`*`, `abc`, true},
+		{`*c`, `abc`, true},
+		{`a*`, `a`, true},
+		{`a*b*c`, `abbbbc`, true},
+		{`a*b*c`, `abbbbcd`, false},
+		{`a*x`, `abc`, false},
+		{`a?c`, `abc`, true},
+		{`a?c`, `ac`, false},
+		{`[a-c]`, `b`, true},
+		{`[a-c]`, `d`, false},
+		{`[!a-c]`, `d`, true},
+		{`[^a-c]`, `a`, false},
+		{`[\]]`, `]`, true},
+		{`\*`, `*`, true},
+		{`\*`, `a`, false},
+		{`日*`, `日本`, true},
+		{`?`, `本`, true},
+	} {
+		if err := validateSegment(testCase.Pattern); err != nil {
+			t.Errorf("%q: %v", testCase.Pattern, err)
+		} else if match := matchSegment(testCase.Pattern, testCase.S); match != testCase.Match {
+			t.Errorf("%q %q: expected %v got %v", testCase.Pattern, testCase.S, testCase.Match, match)
+		}
+	}
+
+	for _, pattern := range []string{`[`, `[]`, `[!]`, `[a-]`, `[-a]`, `a\`, `[a`, `[\`} {
+		if err := validateSegment(pattern); err == nil {
+			t.Errorf("%q: expected an error", pattern)
+		}
+	}
+}
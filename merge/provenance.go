@@ -0,0 +1,264 @@
+package merge
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Origin is a location within a source, see Mode.Source.
+type Origin struct {
+	Source string
+	// Line is the line number within the source, or 0 if unknown, see Mode.SourceLines.
+	Line int
+}
+
+func (o Origin) String() string {
+	if o.Line > 0 {
+		return o.Source + ":" + strconv.Itoa(o.Line)
+	}
+	return o.Source
+}
+
+// Provenance records the origin of a leaf value (a scalar, null, or empty object or array), and the origins of any
+// values it replaced, in the order they were written.
+type Provenance struct {
+	Path       Path
+	Value      interface{}
+	Origin     Origin
+	Overridden []Origin
+}
+
+// Explain returns the provenance of every leaf of v, which must be the result of Filter, Merge, or Patch, sorted by
+// path, where the Origin of any leaf without a recorded provenance is empty.
+func (m *Mode) Explain(v interface{}) []Provenance {
+	result := make([]Provenance, 0)
+	walkLeaves(v, make(Path, 0), func(path Path, v interface{}) {
+		p := Provenance{Path: copyPath(path)}
+		if entry, ok := m.provenance[path.String()]; ok {
+			p = *entry
+		}
+		p.Value = v
+		result = append(result, p)
+	})
+	return result
+}
+
+// record records the current Source as the origin of the leaf v, at path, read from src.
+func (m *Mode) record(v interface{}, path, src Path, overridden []Origin) {
+	if m.provenance == nil {
+		m.provenance = make(map[string]*Provenance)
+	}
+	// empty objects or arrays containing this leaf no longer exist
+	for i := 0; i < len(path); i++ {
+		delete(m.provenance, path[:i].String())
+	}
+	origin := Origin{Source: m.Source}
+	if src != nil {
+		origin.Line = m.SourceLines[src.String()]
+	}
+	key := path.String()
+	if existing, ok := m.provenance[key]; ok {
+		previous := append([]Origin(nil), existing.Overridden...)
+		if existing.Origin != origin {
+			previous = append(previous, existing.Origin)
+		}
+		overridden = appendOrigins(previous, overridden...)
+	}
+	m.provenance[key] = &Provenance{
+		Path:       copyPath(path),
+		Origin:     origin,
+		Overridden: overridden,
+	}
+}
+
+// drop removes the provenance of v, at path, returning the origins of everything removed.
+func (m *Mode) drop(v interface{}, path Path) []Origin {
+	var result []Origin
+	walkLeaves(v, path, func(path Path, _ interface{}) {
+		key := path.String()
+		if entry, ok := m.provenance[key]; ok {
+			result = appendOrigins(result, entry.Overridden...)
+			result = appendOrigins(result, entry.Origin)
+			delete(m.provenance, key)
+		}
+	})
+	return result
+}
+
+// override adds origins to the overridden origins of every leaf of v, at path.
+func (m *Mode) override(v interface{}, path Path, origins []Origin) {
+	if len(origins) == 0 {
+		return
+	}
+	walkLeaves(v, path, func(path Path, _ interface{}) {
+		if entry, ok := m.provenance[path.String()]; ok {
+			overridden := make([]Origin, 0, len(origins)+len(entry.Overridden))
+			for _, origin := range origins {
+				if origin != entry.Origin {
+					overridden = appendOrigins(overridden, origin)
+				}
+			}
+			entry.Overridden = appendOrigins(overridden, entry.Overridden...)
+		}
+	})
+}
+
+// move moves the provenance of each element of list, at path, to the index given by position, or removes it, if the
+// position is negative.
+func (m *Mode) move(list []interface{}, path Path, position func(i int) int) {
+	moved := make(map[int][]*Provenance)
+	for i, v := range list {
+		walkLeaves(v, appendSegment(path, Segment{Value: strconv.Itoa(i), Index: true}), func(path Path, _ interface{}) {
+			key := path.String()
+			if entry, ok := m.provenance[key]; ok {
+				moved[i] = append(moved[i], entry)
+				delete(m.provenance, key)
+			}
+		})
+	}
+	for i, entries := range moved {
+		j := position(i)
+		if j < 0 {
+			continue
+		}
+		for _, entry := range entries {
+			entry.Path[len(path)].Value = strconv.Itoa(j)
+			m.provenance[entry.Path.String()] = entry
+		}
+	}
+}
+
+// Origins returns the distinct origins of every leaf at or below the literal path within v, which must be the result of
+// Filter, Merge, or Patch, in order of their paths, or nil if there are none, e.g. if path doesn't exist.
+func (m *Mode) Origins(v interface{}, path Path) []Origin {
+	v, ok := lookupPath(v, path)
+	if !ok {
+		return nil
+	}
+	return m.leafOrigins(v, copyPath(path))
+}
+
+// leafOrigins returns the distinct origins of every leaf of v, at path.
+func (m *Mode) leafOrigins(v interface{}, path Path) []Origin {
+	var origins []Origin
+	walkLeaves(v, path, func(path Path, _ interface{}) {
+		if entry, ok := m.provenance[path.String()]; ok {
+			origins = appendOrigins(origins, entry.Origin)
+		}
+	})
+	return origins
+}
+
+// origin describes the origins of v, at path, for reporting.
+func (m *Mode) origin(v interface{}, path Path) string {
+	origins := m.leafOrigins(v, path)
+	sources := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		sources = append(sources, origin.String())
+	}
+	sort.Strings(sources)
+	return strings.Join(sources, ", ")
+}
+
+// repatch updates the provenance after a patch (from the current Source) replaced a with b, attributing any leaves
+// that were added or changed to the patch.
+func (m *Mode) repatch(a, b interface{}) {
+	walkLeaves(a, make(Path, 0), func(path Path, v interface{}) {
+		if _, ok := lookupPath(b, path); !ok {
+			m.drop(v, path)
+		}
+	})
+	walkLeaves(b, make(Path, 0), func(path Path, v interface{}) {
+		old, ok := lookupPath(a, path)
+		if ok && reflect.DeepEqual(old, v) {
+			return
+		}
+		var overridden []Origin
+		if ok {
+			overridden = m.drop(old, path)
+		}
+		m.record(v, path, nil, overridden)
+	})
+}
+
+// walkLeaves calls fn for every leaf of v, at path, i.e. every scalar, null, or empty object or array, in order.
+func walkLeaves(v interface{}, path Path, fn func(path Path, v interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			break
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkLeaves(t[k], append(path, Segment{Value: k}), fn)
+		}
+		return
+	case []interface{}:
+		if len(t) == 0 {
+			break
+		}
+		for i, v := range t {
+			walkLeaves(v, append(path, Segment{Value: strconv.Itoa(i), Index: true}), fn)
+		}
+		return
+	}
+	fn(path, v)
+}
+
+// lookupPath returns the value at the literal path within v, if it exists, where a key that is an index (e.g. a.0)
+// addresses an array element, like Match.
+func lookupPath(v interface{}, path Path) (interface{}, bool) {
+	for _, segment := range path {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if segment.Index {
+				return nil, false
+			}
+			var ok bool
+			if v, ok = t[segment.Value]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(segment.Value)
+			if (!segment.Index && !isIndex(segment.Value)) || err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// appendOrigins appends any origins not already in list.
+func appendOrigins(list []Origin, origins ...Origin) []Origin {
+	for _, origin := range origins {
+		found := false
+		for _, existing := range list {
+			if existing == origin {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, origin)
+		}
+	}
+	return list
+}
+
+// appendSegment returns a new path, with segment appended, that doesn't share memory with path.
+func appendSegment(path Path, segment Segment) Path {
+	return append(copyPath(path), segment)
+}
+
+func copyPath(path Path) Path {
+	return append(make(Path, 0, len(path)+1), path...)
+}
@@ -0,0 +1,245 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-test/deep"
+	"strings"
+	"testing"
+)
+
+func TestMode_Explain(t *testing.T) {
+	type input struct {
+		Source, Value string
+		Patch         bool
+		// Set or Unset are paths, to use Mode.Set (with Value) or Mode.Unset
+		Set, Unset string
+	}
+	for _, testCase := range []struct {
+		Name     string
+		Arrays   []string
+		Patch    bool
+		Inputs   []input
+		Expected []string
+	}{
+		{
+			Name: `maps`,
+			Inputs: []input{
+				{Source: `a`, Value: `{"x":1,"y":{"z":2,"w":3},"e":{}}`},
+				{Source: `b`, Value: `{"x":4,"y":{"z":5},"e":{"f":6}}`},
+				{Source: `c`, Value: `{"x":7}`},
+			},
+			Expected: []string{
+				`e.f=6 b`,
+				`x=7 c overrides a, b`,
+				`y.w=3 a`,
+				`y.z=5 b overrides a`,
+			},
+		},
+		{
+			Name: `kind changes`,
+			Inputs: []input{
+				{Source: `a`, Value: `{"x":{"y":1,"z":2},"w":[1,2]}`},
+				{Source: `b`, Value: `{"x":"s","w":{"v":1}}`},
+				{Source: `c`, Value: `{"x":{"y":3}}`},
+			},
+			Expected: []string{
+				`w.v=1 b overrides a`,
+				`x.y=3 c overrides a, b`,
+			},
+		},
+		{
+			Name:  `merge patch`,
+			Patch: true,
+			Inputs: []input{
+				{Source: `a`, Value: `{"x":{"y":1,"z":2},"w":1}`},
+				{Source: `b`, Value: `{"x":{"y":null},"w":null}`},
+			},
+			Expected: []string{
+				`x.z=2 a`,
+			},
+		},
+		{
+			Name: `index merge`,
+			Inputs: []input{
+				{Source: `a`, Value: `[{"x":1,"y":2},3,4]`},
+				{Source: `b`, Value: `[{"x":5}]`},
+			},
+			Expected: []string{
+				`[0].x=5 b overrides a`,
+				`[1]=3 a`,
+				`[2]=4 a`,
+			},
+		},
+		{
+			Name:   `prepend`,
+			Arrays: []string{`prepend`},
+			Inputs: []input{
+				{Source: `a`, Value: `[1,2]`},
+				{Source: `b`, Value: `[3]`},
+				{Source: `c`, Value: `[4,5]`},
+			},
+			Expected: []string{
+				`[0]=4 c`,
+				`[1]=5 c`,
+				`[2]=3 b`,
+				`[3]=1 a`,
+				`[4]=2 a`,
+			},
+		},
+		{
+			Name:   `append`,
+			Arrays: []string{`append`},
+			Inputs: []input{
+				{Source: `a`, Value: `[1]`},
+				{Source: `b`, Value: `[2,[3]]`},
+			},
+			Expected: []string{
+				`[0]=1 a`,
+				`[1]=2 b`,
+				`[2][0]=3 b`,
+			},
+		},
+		{
+			Name:   `replace`,
+			Arrays: []string{`replace`},
+			Inputs: []input{
+				{Source: `a`, Value: `[1,2]`},
+				{Source: `b`, Value: `[]`},
+			},
+			Expected: []string{
+				`=[] b overrides a`,
+			},
+		},
+		{
+			Name:   `unique union`,
+			Arrays: []string{`unique-union`},
+			Inputs: []input{
+				{Source: `a`, Value: `[1,1,2]`},
+				{Source: `b`, Value: `[2,3]`},
+			},
+			Expected: []string{
+				`[0]=1 a`,
+				`[1]=2 a`,
+				`[2]=3 b`,
+			},
+		},
+		{
+			Name:   `merge by key`,
+			Arrays: []string{`merge-by-key:name`},
+			Inputs: []input{
+				{Source: `a`, Value: `[{"name":"x","v":1},{"name":"y","v":2}]`},
+				{Source: `b`, Value: `[{"name":"z","v":3},{"name":"y","v":4}]`},
+			},
+			Expected: []string{
+				`[0].name="x" a`,
+				`[0].v=1 a`,
+				`[1].name="y" b overrides a`,
+				`[1].v=4 b overrides a`,
+				`[2].name="z" b`,
+				`[2].v=3 b`,
+			},
+		},
+		{
+			Name: `json patch`,
+			Inputs: []input{
+				{Source: `a`, Value: `{"x":1,"y":[1,2],"z":{"w":1}}`},
+				{Source: `p`, Value: `[{"op":"replace","path":"/x","value":2},{"op":"remove","path":"/z/w"},{"op":"add","path":"/y/-","value":3}]`, Patch: true},
+			},
+			Expected: []string{
+				`x=2 p overrides a`,
+				`y[0]=1 a`,
+				`y[1]=2 a`,
+				`y[2]=3 p`,
+				`z={} p`,
+			},
+		},
+		{
+			Name: `set and unset`,
+			Inputs: []input{
+				{Source: `a`, Value: `{"x":1,"y":[1,2,3],"z":{"w":1}}`},
+				{Source: `s1`, Set: `x`, Value: `2`},
+				{Source: `s2`, Set: `z[1]`, Value: `{"v":true}`},
+				{Source: `s3`, Unset: `y[0]`},
+				{Source: `s4`, Set: `y[1]`, Value: `4`},
+			},
+			Expected: []string{
+				`x=2 s1 overrides a`,
+				`y[0]=2 a`,
+				`y[1]=4 s4 overrides a`,
+				`z[0]=null s2`,
+				`z[1].v=true s2 overrides a`,
+			},
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			mode := NewMode()
+			mode.MergePatch = testCase.Patch
+			defineArrayRules(t, mode, testCase.Arrays...)
+			var (
+				data interface{}
+				err  error
+			)
+			for i, input := range testCase.Inputs {
+				mode.Source = input.Source
+				switch {
+				case input.Set != ``:
+					data, err = mode.Set(data, parsePath(t, input.Set), parseJSON(t, input.Value))
+				case input.Unset != ``:
+					data, err = mode.Unset(data, parsePath(t, input.Unset))
+				case input.Patch:
+					data, err = mode.Patch(data, parseJSON(t, input.Value))
+				case i == 0:
+					data = mode.Filter(parseJSON(t, input.Value))
+				default:
+					data, err = mode.Merge(data, parseJSON(t, input.Value))
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			actual := make([]string, 0)
+			for _, p := range mode.Explain(data) {
+				value, _ := json.Marshal(p.Value)
+				s := fmt.Sprintf("%s=%s %s", p.Path, value, p.Origin)
+				if len(p.Overridden) != 0 {
+					overridden := make([]string, 0)
+					for _, origin := range p.Overridden {
+						overridden = append(overridden, origin.String())
+					}
+					s += " overrides " + strings.Join(overridden, ", ")
+				}
+				actual = append(actual, s)
+			}
+			if diff := deep.Equal(testCase.Expected, actual); diff != nil {
+				t.Errorf("%v\n%s", diff, strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
+
+func TestMode_Origins(t *testing.T) {
+	mode := NewMode()
+	mode.Source = `a`
+	v := mode.Filter(parseJSON(t, `{"x":{"y":1,"z":2},"w":[1]}`))
+	mode.Source = `b`
+	v, err := mode.Merge(v, parseJSON(t, `{"x":{"z":3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, testCase := range []struct {
+		Path     string
+		Expected []Origin
+	}{
+		{``, []Origin{{Source: `a`}, {Source: `b`}}},
+		{`x`, []Origin{{Source: `a`}, {Source: `b`}}},
+		{`x.z`, []Origin{{Source: `b`}}},
+		{`w[0]`, []Origin{{Source: `a`}}},
+		{`x.missing`, nil},
+		{`w[1]`, nil},
+	} {
+		if diff := deep.Equal(testCase.Expected, mode.Origins(v, parsePath(t, testCase.Path))); diff != nil {
+			t.Errorf("%s: %v", testCase.Path, diff)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package merge
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxArrayGrowth is the maximum number of elements by which Set may extend an array, which guards against paths like
+// a[99999999999] exhausting memory.
+const maxArrayGrowth = 1 << 16
+
+// Get returns the value at the literal path within v, if it exists, where a key that is an index (e.g. a.0) addresses
+// an array element, like Match.
+func Get(v interface{}, path Path) (interface{}, bool, error) {
+	if err := validateLiteral(path); err != nil {
+		return nil, false, err
+	}
+	v, ok := lookupPath(v, path)
+	return v, ok, nil
+}
+
+// Set sets the value at the literal path within v, which must be the result of Filter, Merge, or Patch, returning the
+// result. Any missing objects or arrays are created, where an index (e.g. [0]) addresses an array, and anything else
+// an object, replacing any existing value of a different kind, except that a key that is an index (e.g. a.0) addresses
+// an existing array, like Match. Arrays are extended with nulls, as necessary, by up to maxArrayGrowth elements. The
+// value is filtered, as for Filter, and is ignored if path is excluded.
+func (m *Mode) Set(v interface{}, path Path, value interface{}) (interface{}, error) {
+	if err := validateLiteral(path); err != nil {
+		return nil, err
+	}
+	path = copyPath(path)
+	if !m.includeValue(path, value) {
+		return v, nil
+	}
+	var overridden []Origin
+	result, err := m.set(v, path, 0, value, &overridden)
+	if err != nil {
+		return nil, err
+	}
+	leaf, _ := lookupPath(result, path)
+	m.override(leaf, path, overridden)
+	return result, nil
+}
+
+// Unset removes the value at the literal path within v, which must be the result of Filter, Merge, or Patch,
+// returning the result, where removing an array element shifts any elements that follow it. Removing the root
+// results in null, and paths that don't exist are ignored.
+func (m *Mode) Unset(v interface{}, path Path) (interface{}, error) {
+	if err := validateLiteral(path); err != nil {
+		return nil, err
+	}
+	if _, ok := lookupPath(v, path); !ok {
+		return v, nil
+	}
+	return m.unset(v, copyPath(path), 0), nil
+}
+
+func (m *Mode) set(v interface{}, path Path, i int, value interface{}, overridden *[]Origin) (interface{}, error) {
+	if i == len(path) {
+		*overridden = appendOrigins(*overridden, m.drop(v, path)...)
+		return m.filter(value, path, path), nil
+	}
+
+	segment := path[i]
+	list, isList := v.([]interface{})
+	if !segment.Index && isList && isIndex(segment.Value) {
+		// the path is modified, so that it is consistent with the result, e.g. for provenance
+		segment.Index = true
+		path[i] = segment
+	}
+
+	if segment.Index {
+		if !isList && v != nil {
+			*overridden = appendOrigins(*overridden, m.drop(v, copyPath(path[:i]))...)
+		}
+		n, err := strconv.Atoi(segment.Value)
+		if err != nil || n > len(list)+maxArrayGrowth {
+			return nil, fmt.Errorf("index %s of '%s' is out of range, as arrays may be extended by at most %d elements", segment.Value, path[:i], maxArrayGrowth)
+		}
+		size := len(list)
+		if n >= size {
+			size = n + 1
+		}
+		result := make([]interface{}, size)
+		copy(result, list)
+		for j := len(list); j < n; j++ {
+			m.record(nil, appendSegment(path[:i], Segment{Value: strconv.Itoa(j), Index: true}), nil, nil)
+		}
+		if result[n], err = m.set(result[n], path, i+1, value, overridden); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	t, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		*overridden = appendOrigins(*overridden, m.drop(v, copyPath(path[:i]))...)
+	}
+	result := make(map[string]interface{}, len(t)+1)
+	for k, v := range t {
+		result[k] = v
+	}
+	child, err := m.set(result[segment.Value], path, i+1, value, overridden)
+	if err != nil {
+		return nil, err
+	}
+	result[segment.Value] = child
+	return result, nil
+}
+
+func (m *Mode) unset(v interface{}, path Path, i int) interface{} {
+	if i == len(path) {
+		m.drop(v, path)
+		return nil
+	}
+
+	segment := path[i]
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			result[k] = v
+		}
+		if i == len(path)-1 {
+			m.drop(t[segment.Value], path)
+			delete(result, segment.Value)
+		} else {
+			result[segment.Value] = m.unset(t[segment.Value], path, i+1)
+		}
+		if len(result) == 0 {
+			m.record(result, path[:i], nil, nil)
+		}
+		return result
+
+	case []interface{}:
+		// the path exists, so the segment is an index, which may have been a key, see lookupPath
+		path[i].Index = true
+		n, _ := strconv.Atoi(segment.Value)
+		if i != len(path)-1 {
+			result := append([]interface{}(nil), t...)
+			result[n] = m.unset(t[n], path, i+1)
+			return result
+		}
+		m.drop(t[n], path)
+		m.move(t, path[:i], func(j int) int {
+			if j > n {
+				return j - 1
+			}
+			return j
+		})
+		result := make([]interface{}, 0, len(t)-1)
+		result = append(result, t[:n]...)
+		result = append(result, t[n+1:]...)
+		if len(result) == 0 {
+			m.record(result, path[:i], nil, nil)
+		}
+		return result
+	}
+
+	// unreachable, as the path exists
+	return v
+}
+
+func validateLiteral(path Path) error {
+	if !path.Literal() {
+		return fmt.Errorf("invalid path '%s': wildcards aren't supported", path)
+	}
+	return nil
+}
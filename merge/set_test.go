@@ -0,0 +1,139 @@
+package merge
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestMode_Set(t *testing.T) {
+	for _, testCase := range []struct {
+		Name      string
+		Base      string
+		Path      string
+		Value     string
+		Blacklist []string
+		Expected  string
+	}{
+		{`existing key`, `{"a": {"b": 1}}`, `a.b`, `2`, nil, `{"a": {"b": 2}}`},
+		{`new key`, `{"a": {"b": 1}}`, `a.c`, `"x"`, nil, `{"a": {"b": 1, "c": "x"}}`},
+		{`intermediate objects`, `{}`, `a.b.c`, `true`, nil, `{"a": {"b": {"c": true}}}`},
+		{`intermediate arrays`, `{}`, `a[1].b`, `1`, nil, `{"a": [null, {"b": 1}]}`},
+		{`existing element`, `{"a": [1, 2, 3]}`, `a[1]`, `{"x": 1}`, nil, `{"a": [1, {"x": 1}, 3]}`},
+		{`extends arrays`, `{"a": [1]}`, `a[3]`, `4`, nil, `{"a": [1, null, null, 4]}`},
+		{`key index of existing array`, `{"a": [1, 2, 3]}`, `a.0`, `9`, nil, `{"a": [9, 2, 3]}`},
+		{`key index extends arrays`, `{"a": [1]}`, `a.2.b`, `2`, nil, `{"a": [1, null, {"b": 2}]}`},
+		{`key index of objects`, `{"a": {"b": 1}}`, `a.0`, `2`, nil, `{"a": {"b": 1, "0": 2}}`},
+		{`replaces scalars`, `{"a": 1}`, `a.b`, `2`, nil, `{"a": {"b": 2}}`},
+		{`replaces objects with arrays`, `{"a": {"b": 1}}`, `a[0]`, `2`, nil, `{"a": [2]}`},
+		{`quoted keys`, `{}`, `["a.b"]`, `1`, nil, `{"a.b": 1}`},
+		{`root`, `{"a": 1}`, ``, `[1]`, nil, `[1]`},
+		{`null base`, `null`, `a`, `1`, nil, `{"a": 1}`},
+		{`excluded`, `{"a": 1}`, `b`, `2`, []string{`b`}, `{"a": 1}`},
+		{`filtered`, `{"a": 1}`, `b`, `{"c": 1, "d": 2}`, []string{`b.d`}, `{"a": 1, "b": {"c": 1}}`},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			mode, err := New(Options{Exclude: testCase.Blacklist})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path, err := ParsePath(testCase.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			base := parseJSON(t, testCase.Base)
+			data := mode.Filter(base)
+			result, err := mode.Set(data, path, parseJSON(t, testCase.Value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Expected), result); diff != nil {
+				t.Error(diff)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Base), base); diff != nil {
+				t.Errorf("base modified: %v", diff)
+			}
+		})
+	}
+
+	if _, err := NewMode().Set(nil, Path{{Value: `*`, Pattern: true}}, 1); err == nil {
+		t.Error("expected an error")
+	}
+	for _, p := range []string{`a[99999999999]`, `a[99999999999999999999]`} {
+		if _, err := NewMode().Set(parseJSON(t, `{"a": [1]}`), parsePath(t, p), 1); err == nil {
+			t.Errorf("%q: expected an error", p)
+		}
+	}
+}
+
+func TestMode_Unset(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Base     string
+		Path     string
+		Expected string
+	}{
+		{`key`, `{"a": {"b": 1, "c": 2}}`, `a.b`, `{"a": {"c": 2}}`},
+		{`last key`, `{"a": {"b": 1}}`, `a.b`, `{"a": {}}`},
+		{`element`, `{"a": [1, 2, 3]}`, `a[1]`, `{"a": [1, 3]}`},
+		{`key index`, `{"a": [1, 2, 3]}`, `a.1`, `{"a": [1, 3]}`},
+		{`nested key index`, `{"a": [{"b": 1, "c": 2}]}`, `a.0.b`, `{"a": [{"c": 2}]}`},
+		{`nested element`, `{"a": [{"b": 1, "c": 2}]}`, `a[0].b`, `{"a": [{"c": 2}]}`},
+		{`missing key`, `{"a": 1}`, `b.c`, `{"a": 1}`},
+		{`missing element`, `{"a": [1]}`, `a[1]`, `{"a": [1]}`},
+		{`kind mismatch`, `{"a": [1]}`, `a.b`, `{"a": [1]}`},
+		{`root`, `{"a": 1}`, ``, `null`},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			path, err := ParsePath(testCase.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			base := parseJSON(t, testCase.Base)
+			mode := NewMode()
+			result, err := mode.Unset(mode.Filter(base), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Expected), result); diff != nil {
+				t.Error(diff)
+			}
+			if diff := deep.Equal(parseJSON(t, testCase.Base), base); diff != nil {
+				t.Errorf("base modified: %v", diff)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	v := parseJSON(t, `{"a": {"b": [1, {"c": null}]}, "d.e": true}`)
+	for _, testCase := range []struct {
+		Path     string
+		Expected string
+		Found    bool
+	}{
+		{``, `{"a": {"b": [1, {"c": null}]}, "d.e": true}`, true},
+		{`a.b[0]`, `1`, true},
+		{`a.b[1].c`, `null`, true},
+		{`a.b.1.c`, `null`, true},
+		{`a.b.01`, `null`, false},
+		{`["d.e"]`, `true`, true},
+		{`a.b[2]`, `null`, false},
+		{`a.b.c`, `null`, false},
+		{`a[0]`, `null`, false},
+		{`d.e`, `null`, false},
+	} {
+		value, ok, err := Get(v, parsePath(t, testCase.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != testCase.Found {
+			t.Errorf("%q: expected found %v", testCase.Path, testCase.Found)
+		}
+		if diff := deep.Equal(parseJSON(t, testCase.Expected), value); diff != nil {
+			t.Errorf("%q: %v", testCase.Path, diff)
+		}
+	}
+	if _, _, err := Get(v, parsePath(t, `a.*`)); err == nil {
+		t.Error("expected an error")
+	}
+}
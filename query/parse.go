@@ -0,0 +1,438 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError indicates that a query is invalid, at Offset, the position (in runes) within Query.
+type SyntaxError struct {
+	Query   string
+	Offset  int
+	Message string
+}
+
+type queryParser struct {
+	query string
+	r     []rune
+	i     int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query '%s' at %d: %s", e.Query, e.Offset, e.Message)
+}
+
+// Caret returns the query, and a second line, pointing at the offset of the error.
+func (e *SyntaxError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Offset) + "^"
+}
+
+func (p *queryParser) fail(format string, args ...interface{}) error {
+	return &SyntaxError{Query: p.query, Offset: p.i, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) eof() bool { return p.i >= len(p.r) }
+
+func (p *queryParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.r[p.i]
+}
+
+func (p *queryParser) peekAt(n int) rune {
+	if p.i+n >= len(p.r) {
+		return 0
+	}
+	return p.r[p.i+n]
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.r[p.i]) {
+		p.i++
+	}
+}
+
+// consume skips whitespace, then the literal s, if it is next.
+func (p *queryParser) consume(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(string(p.r[p.i:]), s) {
+		p.i += len([]rune(s))
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(s string) error {
+	if !p.consume(s) {
+		return p.fail("expected '%s'", s)
+	}
+	return nil
+}
+
+// parseQuery parses a whole query, which may start with '$', or a name, e.g. a.b, or a bracket, e.g. [0].
+func (p *queryParser) parseQuery() ([]segment, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.fail("empty query")
+	}
+	var segments []segment
+	switch c := p.peek(); {
+	case c == '$':
+		p.i++
+	case c == '.' || c == '[':
+	case isNameStart(c) || c == '*':
+		s, err := p.parseDotted()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	default:
+		return nil, p.fail("unexpected %q", c)
+	}
+	rest, err := p.parseSegments(false)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.fail("unexpected %q", p.peek())
+	}
+	return append(segments, rest...), nil
+}
+
+// parseSegments parses any segments, following the root, or current node, stopping at anything else, if relative
+// (within a filter).
+func (p *queryParser) parseSegments(relative bool) ([]segment, error) {
+	var segments []segment
+	for {
+		if relative {
+			// whitespace may separate a relative path from an operator
+			if c := p.peek(); c != '.' && c != '[' {
+				return segments, nil
+			}
+		} else {
+			p.skipSpace()
+			if p.eof() {
+				return segments, nil
+			}
+		}
+		switch p.peek() {
+		case '.':
+			p.i++
+			if p.peek() == '.' {
+				p.i++
+				var s segment
+				var err error
+				if p.peek() == '[' {
+					s, err = p.parseBracket()
+				} else {
+					s, err = p.parseDotted()
+				}
+				if err != nil {
+					return nil, err
+				}
+				s.descendant = true
+				segments = append(segments, s)
+				continue
+			}
+			s, err := p.parseDotted()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, s)
+		case '[':
+			s, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, s)
+		default:
+			if relative {
+				return segments, nil
+			}
+			return nil, p.fail("expected '.' or '['")
+		}
+	}
+}
+
+// parseDotted parses a name or wildcard, following a dot.
+func (p *queryParser) parseDotted() (segment, error) {
+	if p.peek() == '*' {
+		p.i++
+		return segment{selectors: []selector{wildcardSelector{}}}, nil
+	}
+	if !isNameStart(p.peek()) {
+		return segment{}, p.fail("expected a name or '*'")
+	}
+	start := p.i
+	for !p.eof() && isNameChar(p.r[p.i]) {
+		p.i++
+	}
+	return segment{selectors: []selector{nameSelector(string(p.r[start:p.i]))}}, nil
+}
+
+// parseBracket parses a comma separated list of selectors, within brackets.
+func (p *queryParser) parseBracket() (segment, error) {
+	if err := p.expect("["); err != nil {
+		return segment{}, err
+	}
+	var s segment
+	for {
+		sel, err := p.parseSelector()
+		if err != nil {
+			return segment{}, err
+		}
+		s.selectors = append(s.selectors, sel)
+		if p.consume(",") {
+			continue
+		}
+		if err := p.expect("]"); err != nil {
+			return segment{}, err
+		}
+		return s, nil
+	}
+}
+
+func (p *queryParser) parseSelector() (selector, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '*':
+		p.i++
+		return wildcardSelector{}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return nameSelector(s), nil
+	case c == '?':
+		p.i++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector{expr: e}, nil
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		return p.parseIndexOrSlice()
+	}
+	return nil, p.fail("expected a selector")
+}
+
+// parseIndexOrSlice parses an index, e.g. -1, or a slice, e.g. 1:-1:2.
+func (p *queryParser) parseIndexOrSlice() (selector, error) {
+	var (
+		values [3]*int
+		n      int
+	)
+	for {
+		p.skipSpace()
+		if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+			v, err := p.parseInt()
+			if err != nil {
+				return nil, err
+			}
+			values[n] = &v
+		}
+		p.skipSpace()
+		if p.peek() != ':' {
+			break
+		}
+		if n == 2 {
+			return nil, p.fail("too many ':' in slice")
+		}
+		p.i++
+		n++
+	}
+	if n == 0 {
+		if values[0] == nil {
+			return nil, p.fail("expected an index")
+		}
+		return indexSelector(*values[0]), nil
+	}
+	if values[2] != nil && *values[2] == 0 {
+		return nil, p.fail("slice step must not be zero")
+	}
+	return sliceSelector{start: values[0], end: values[1], step: values[2]}, nil
+}
+
+func (p *queryParser) parseInt() (int, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	for !p.eof() && p.r[p.i] >= '0' && p.r[p.i] <= '9' {
+		p.i++
+	}
+	v, err := strconv.Atoi(string(p.r[start:p.i]))
+	if err != nil {
+		p.i = start
+		return 0, p.fail("invalid integer")
+	}
+	return v, nil
+}
+
+// parseString parses a single or double quoted string, supporting backslash escapes.
+func (p *queryParser) parseString() (string, error) {
+	quote := p.r[p.i]
+	start := p.i
+	p.i++
+	var b strings.Builder
+	for !p.eof() {
+		c := p.r[p.i]
+		switch c {
+		case quote:
+			p.i++
+			return b.String(), nil
+		case '\\':
+			p.i++
+			if p.eof() {
+				break
+			}
+			switch e := p.r[p.i]; e {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			default:
+				b.WriteRune(e)
+			}
+		default:
+			b.WriteRune(c)
+		}
+		p.i++
+	}
+	p.i = start
+	return "", p.fail("unterminated string")
+}
+
+// parseOr parses a filter expression, i.e. a logical or, of logical ands.
+func (p *queryParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (expr, error) {
+	p.skipSpace()
+	if p.peek() == '!' && p.peekAt(1) != '=' {
+		p.i++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.consume("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (expr, error) {
+	p.skipSpace()
+	start := p.i
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparisonExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	q, ok := left.(queryOperand)
+	if !ok {
+		p.i = start
+		return nil, p.fail("expected a comparison")
+	}
+	return existsExpr{q}, nil
+}
+
+func (p *queryParser) parseOperand() (operand, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.i++
+		segments, err := p.parseSegments(true)
+		if err != nil {
+			return nil, err
+		}
+		return queryOperand{relative: c == '@', segments: segments}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literalOperand{s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.i
+		p.i++
+		for !p.eof() && strings.ContainsRune("0123456789.eE+-", p.r[p.i]) {
+			p.i++
+		}
+		f, err := strconv.ParseFloat(string(p.r[start:p.i]), 64)
+		if err != nil {
+			p.i = start
+			return nil, p.fail("invalid number")
+		}
+		return literalOperand{f}, nil
+	}
+	for _, literal := range []struct {
+		s string
+		v interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if strings.HasPrefix(string(p.r[p.i:]), literal.s) && !isNameChar(p.peekAt(len(literal.s))) {
+			p.i += len(literal.s)
+			return literalOperand{literal.v}, nil
+		}
+	}
+	return nil, p.fail("expected '@', '$', or a literal")
+}
+
+func isNameStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isNameChar(c rune) bool {
+	return c == '_' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
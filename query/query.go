@@ -0,0 +1,362 @@
+// Package query implements a subset of JSONPath (RFC 9535), for selecting and projecting values like those returned
+// by parser.Reader.
+//
+// Syntax:
+//
+//	query:     [ '$' ] { segment }, where the first segment may be a name, without a leading dot, e.g. a.b
+//	segment:   '.' name | '.*' | '[' selector { ',' selector } ']' | '..' ( name | '*' | '[' ... ']' )
+//	selector:  quoted | index | slice | '*' | '?' filter
+//	slice:     [ start ] ':' [ end ] [ ':' [ step ] ]
+//	filter:    filter '||' filter | filter '&&' filter | '!' filter | '(' filter ')' | operand [ op operand ]
+//	operand:   '@' { segment } | '$' { segment } | quoted | number | true | false | null
+//	op:        '==' | '!=' | '<' | '<=' | '>' | '>='
+//
+// Where '..' selects from the current node and all of its descendants, negative indexes count from the end of an
+// array, and a filter operand that is a query, without an op, tests that it selects anything. Objects are iterated
+// in order of their keys.
+//
+// Example: services[?@.enabled == true].image selects the image of every enabled service.
+package query
+
+import (
+	"reflect"
+	"sort"
+)
+
+type (
+	// Query is a parsed query, see Parse.
+	Query struct {
+		query    string
+		segments []segment
+	}
+
+	segment struct {
+		// descendant indicates a '..' segment, which applies the selectors to every descendant.
+		descendant bool
+		selectors  []selector
+	}
+
+	selector interface {
+		// selectFrom appends the values selected from v to result, where root is the value the query is applied to.
+		selectFrom(v, root interface{}, result []interface{}) []interface{}
+	}
+
+	nameSelector     string
+	indexSelector    int
+	wildcardSelector struct{}
+	sliceSelector    struct{ start, end, step *int }
+	filterSelector   struct{ expr expr }
+
+	expr interface {
+		eval(current, root interface{}) bool
+	}
+
+	orExpr         struct{ left, right expr }
+	andExpr        struct{ left, right expr }
+	notExpr        struct{ expr expr }
+	existsExpr     struct{ query queryOperand }
+	comparisonExpr struct {
+		op          string
+		left, right operand
+	}
+
+	operand interface {
+		// value returns the value of the operand, or false if it selects nothing.
+		value(current, root interface{}) (interface{}, bool)
+	}
+
+	queryOperand struct {
+		relative bool
+		segments []segment
+	}
+	literalOperand struct{ v interface{} }
+)
+
+// Parse parses a query, returning a *SyntaxError if it is invalid.
+func Parse(s string) (*Query, error) {
+	p := queryParser{query: s, r: []rune(s)}
+	segments, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{query: s, segments: segments}, nil
+}
+
+// Select parses and applies the query s to v, see Query.Select.
+func Select(s string, v interface{}) ([]interface{}, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(v), nil
+}
+
+// Apply parses and applies the query s to v, see Query.Apply.
+func Apply(s string, v interface{}) (interface{}, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return q.Apply(v), nil
+}
+
+func (q *Query) String() string { return q.query }
+
+// Select returns every value selected by the query, in order.
+func (q *Query) Select(v interface{}) []interface{} {
+	return selectSegments(q.segments, v, v)
+}
+
+// Singular returns true if the query may select at most one value, i.e. it has only names and indexes.
+func (q *Query) Singular() bool {
+	for _, s := range q.segments {
+		if s.descendant || len(s.selectors) != 1 {
+			return false
+		}
+		switch s.selectors[0].(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the result of the query, either the selected value (or nil, if there wasn't one), if the query is
+// Singular, otherwise an array of every selected value, i.e. a projection.
+func (q *Query) Apply(v interface{}) interface{} {
+	result := q.Select(v)
+	if q.Singular() {
+		if len(result) == 0 {
+			return nil
+		}
+		return result[0]
+	}
+	if result == nil {
+		result = make([]interface{}, 0)
+	}
+	return result
+}
+
+func selectSegments(segments []segment, v, root interface{}) []interface{} {
+	nodes := []interface{}{v}
+	for _, s := range segments {
+		var next []interface{}
+		for _, node := range nodes {
+			if s.descendant {
+				walk(node, func(v interface{}) {
+					for _, sel := range s.selectors {
+						next = sel.selectFrom(v, root, next)
+					}
+				})
+				continue
+			}
+			for _, sel := range s.selectors {
+				next = sel.selectFrom(node, root, next)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// walk calls fn for v, then every descendant of v, in order.
+func walk(v interface{}, fn func(v interface{})) {
+	fn(v)
+	for _, child := range children(v) {
+		walk(child, fn)
+	}
+}
+
+// children returns the values of an object (in order of their keys), or the elements of an array.
+func children(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		result := make([]interface{}, 0, len(t))
+		for _, k := range keys {
+			result = append(result, t[k])
+		}
+		return result
+	case []interface{}:
+		return t
+	}
+	return nil
+}
+
+func (s nameSelector) selectFrom(v, root interface{}, result []interface{}) []interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if child, ok := m[string(s)]; ok {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+func (s indexSelector) selectFrom(v, root interface{}, result []interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		i := int(s)
+		if i < 0 {
+			i += len(list)
+		}
+		if i >= 0 && i < len(list) {
+			result = append(result, list[i])
+		}
+	}
+	return result
+}
+
+func (s wildcardSelector) selectFrom(v, root interface{}, result []interface{}) []interface{} {
+	return append(result, children(v)...)
+}
+
+func (s sliceSelector) selectFrom(v, root interface{}, result []interface{}) []interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return result
+	}
+	n := len(list)
+	step := 1
+	if s.step != nil {
+		step = *s.step
+	}
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += n
+		}
+		if step > 0 {
+			return clamp(i, 0, n)
+		}
+		return clamp(i, -1, n-1)
+	}
+	if step > 0 {
+		for i, end := bound(s.start, 0), bound(s.end, n); i < end; i += step {
+			result = append(result, list[i])
+		}
+	} else {
+		for i, end := bound(s.start, n-1), bound(s.end, -1); i > end; i += step {
+			result = append(result, list[i])
+		}
+	}
+	return result
+}
+
+func clamp(i, lower, upper int) int {
+	if i < lower {
+		return lower
+	}
+	if i > upper {
+		return upper
+	}
+	return i
+}
+
+func (s filterSelector) selectFrom(v, root interface{}, result []interface{}) []interface{} {
+	for _, child := range children(v) {
+		if s.expr.eval(child, root) {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+func (e orExpr) eval(current, root interface{}) bool {
+	return e.left.eval(current, root) || e.right.eval(current, root)
+}
+
+func (e andExpr) eval(current, root interface{}) bool {
+	return e.left.eval(current, root) && e.right.eval(current, root)
+}
+
+func (e notExpr) eval(current, root interface{}) bool {
+	return !e.expr.eval(current, root)
+}
+
+func (e existsExpr) eval(current, root interface{}) bool {
+	_, ok := e.query.value(current, root)
+	return ok
+}
+
+func (e comparisonExpr) eval(current, root interface{}) bool {
+	a, aOK := e.left.value(current, root)
+	b, bOK := e.right.value(current, root)
+	switch e.op {
+	case "==":
+		return equal(a, aOK, b, bOK)
+	case "!=":
+		return !equal(a, aOK, b, bOK)
+	}
+	if !aOK || !bOK {
+		return false
+	}
+	var c int
+	switch ta := a.(type) {
+	case float64:
+		tb, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		c = compare(ta < tb, ta > tb)
+	case string:
+		tb, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c = compare(ta < tb, ta > tb)
+	default:
+		return false
+	}
+	switch e.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func compare(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// equal compares two operand values, where two operands that select nothing are equal.
+func equal(a interface{}, aOK bool, b interface{}, bOK bool) bool {
+	if !aOK || !bOK {
+		return aOK == bOK
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (o queryOperand) value(current, root interface{}) (interface{}, bool) {
+	v := root
+	if o.relative {
+		v = current
+	}
+	result := selectSegments(o.segments, v, root)
+	if len(result) == 0 {
+		return nil, false
+	}
+	return result[0], true
+}
+
+func (o literalOperand) value(current, root interface{}) (interface{}, bool) {
+	return o.v, true
+}
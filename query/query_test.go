@@ -0,0 +1,126 @@
+package query
+
+import (
+	"github.com/go-test/deep"
+	"github.com/joeycumines/go-configger/parser"
+	"strings"
+	"testing"
+)
+
+const testDocument = `{
+  "name": "example",
+  "services": [
+    {"name": "api", "image": "api:1", "enabled": true, "replicas": 3, "tags": ["a", "b"]},
+    {"name": "worker", "image": "worker:2", "enabled": false, "replicas": 1},
+    {"name": "cron", "image": "cron:3", "enabled": true, "tags": []}
+  ],
+  "db": {"host": "localhost", "port": 5432, "my-key": {"name": "nested"}},
+  "limits": {"max": 3}
+}`
+
+func TestQuery_Apply(t *testing.T) {
+	v := parseJSON(t, testDocument)
+	for _, testCase := range []struct {
+		Query    string
+		Expected string
+	}{
+		{`$`, testDocument},
+		{`name`, `"example"`},
+		{`$.name`, `"example"`},
+		{`db.port`, `5432`},
+		{`db.my-key.name`, `"nested"`},
+		{`$['db']["host"]`, `"localhost"`},
+		{`db.missing`, `null`},
+		{`services[0].name`, `"api"`},
+		{`services[-1].name`, `"cron"`},
+		{`services[3]`, `null`},
+		{`services[*].image`, `["api:1", "worker:2", "cron:3"]`},
+		{`services.*.name`, `["api", "worker", "cron"]`},
+		{`services[0,2].name`, `["api", "cron"]`},
+		{`services[1:].name`, `["worker", "cron"]`},
+		{`services[:-1].name`, `["api", "worker"]`},
+		{`services[::-1].name`, `["cron", "worker", "api"]`},
+		{`services[::2].name`, `["api", "cron"]`},
+		{`services[5:].name`, `[]`},
+		{`db.*`, `["localhost", {"name": "nested"}, 5432]`},
+		{`$..name`, `["example", "nested", "api", "worker", "cron"]`},
+		{`..tags[0]`, `["a"]`},
+		{`services[?@.enabled == true].image`, `["api:1", "cron:3"]`},
+		{`services[?(@.enabled)].name`, `["api", "worker", "cron"]`},
+		{`services[?(@.tags)].name`, `["api", "cron"]`},
+		{`services[?!@.tags].name`, `["worker"]`},
+		{`services[?@.replicas >= 2].name`, `["api"]`},
+		{`services[?@.replicas < $.limits.max].name`, `["worker"]`},
+		{`services[?@.replicas != 3].name`, `["worker", "cron"]`},
+		{`services[?@.enabled && @.replicas > 1 || @.name == 'cron'].name`, `["api", "cron"]`},
+		{`services[?@.enabled && (@.replicas > 1 || @.name == "cron")].name`, `["api", "cron"]`},
+		{`services[?@.name > "b"].name`, `["worker", "cron"]`},
+		{`services[?@.name < 1].name`, `[]`},
+		{`services[?@.missing == null].name`, `[]`},
+		{`services[?@.missing == @.other].name`, `["api", "worker", "cron"]`},
+		{`services[?@.tags[0] == 'a'].name`, `["api"]`},
+		{`services[?@.replicas == -1.5e0].name`, `[]`},
+		{`..[?@.port].host`, `["localhost"]`},
+		{` services [ 0 ].name `, `"api"`},
+	} {
+		actual, err := Apply(testCase.Query, v)
+		if err != nil {
+			t.Errorf("%q: %v", testCase.Query, err)
+			continue
+		}
+		if diff := deep.Equal(parseJSON(t, testCase.Expected), actual); diff != nil {
+			t.Errorf("%q: %v", testCase.Query, diff)
+		}
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, testCase := range []struct {
+		Query   string
+		Offset  int
+		Message string
+	}{
+		{``, 0, `empty query`},
+		{`services[*.image`, 10, `expected ']'`},
+		{`services[`, 9, `expected a selector`},
+		{`a..`, 3, `expected a name or '*'`},
+		{`a.1`, 2, `expected a name or '*'`},
+		{`#`, 0, `unexpected '#'`},
+		{`a b`, 2, `expected '.' or '['`},
+		{`a['b`, 2, `unterminated string`},
+		{`a[1:2:3:4]`, 7, `too many ':' in slice`},
+		{`a[::0]`, 5, `slice step must not be zero`},
+		{`a[?@.b ==]`, 9, `expected '@', '$', or a literal`},
+		{`a[?1]`, 3, `expected a comparison`},
+		{`a[?(@.b]`, 7, `expected ')'`},
+		{`a[?@.b == 1e]`, 10, `invalid number`},
+		{`a[-]`, 2, `invalid integer`},
+	} {
+		_, err := Parse(testCase.Query)
+		syntaxError, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error, got %v", testCase.Query, err)
+			continue
+		}
+		if syntaxError.Offset != testCase.Offset || syntaxError.Message != testCase.Message {
+			t.Errorf("%q: unexpected error at %d: %s", testCase.Query, syntaxError.Offset, syntaxError.Message)
+		}
+	}
+
+	_, err := Parse(`services[*.image`)
+	if s := err.Error(); s != `invalid query 'services[*.image' at 10: expected ']'` {
+		t.Error(s)
+	}
+	if s := err.(*SyntaxError).Caret(); s != "services[*.image\n          ^" {
+		t.Error(s)
+	}
+}
+
+func parseJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := parser.JSONRead(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"sort"
+)
+
+// Draft is the $schema of every inferred schema, see Infer.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type (
+	// InferOptions configures Infer.
+	InferOptions struct {
+		// MaxEnum is the maximum number of distinct values of a string for an enum, where strings are only an enum
+		// if at least one value was observed more than once, or 0 to disable enums.
+		MaxEnum int
+	}
+
+	// shape accumulates every value observed at a path.
+	shape struct {
+		types map[string]bool
+		// objects is the number of objects observed, for determining the required properties
+		objects    int
+		properties map[string]*shape
+		// count is the number of times a property was observed
+		count   int
+		items   *shape
+		strings map[string]int
+	}
+)
+
+// typeOrder is the order of types, in inferred schemas.
+var typeOrder = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Infer returns a schema document describing every example, e.g. as read by parser.Reader, where properties are
+// required if they exist in every object observed at their path, and array elements are described by a single schema.
+// The result is a starting point, rather than a strict definition, e.g. there is no restriction on additional
+// properties.
+func Infer(options InferOptions, examples ...interface{}) map[string]interface{} {
+	root := new(shape)
+	for _, v := range examples {
+		root.observe(v)
+	}
+	result := root.schema(options)
+	result["$schema"] = Draft
+	return result
+}
+
+func (s *shape) observe(v interface{}) {
+	if s.types == nil {
+		s.types = make(map[string]bool)
+	}
+	s.types[typeOf(v)] = true
+	switch t := v.(type) {
+	case map[string]interface{}:
+		s.objects++
+		if s.properties == nil {
+			s.properties = make(map[string]*shape)
+		}
+		for k, v := range t {
+			p, ok := s.properties[k]
+			if !ok {
+				p = new(shape)
+				s.properties[k] = p
+			}
+			p.count++
+			p.observe(v)
+		}
+	case []interface{}:
+		for _, v := range t {
+			if s.items == nil {
+				s.items = new(shape)
+			}
+			s.items.observe(v)
+		}
+	case string:
+		if s.strings == nil {
+			s.strings = make(map[string]int)
+		}
+		s.strings[t]++
+	}
+}
+
+func (s *shape) schema(options InferOptions) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	var types []interface{}
+	for _, t := range typeOrder {
+		// integers are numbers
+		if s.types[t] && (t != "integer" || !s.types["number"]) {
+			types = append(types, t)
+		}
+	}
+	switch len(types) {
+	case 0:
+		// nothing was observed, e.g. the items of empty arrays
+		return result
+	case 1:
+		result["type"] = types[0]
+	default:
+		result["type"] = types
+	}
+
+	if s.properties != nil {
+		keys := make([]string, 0, len(s.properties))
+		for k := range s.properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		properties := make(map[string]interface{}, len(keys))
+		var required []interface{}
+		for _, k := range keys {
+			p := s.properties[k]
+			properties[k] = p.schema(options)
+			if p.count == s.objects {
+				required = append(required, k)
+			}
+		}
+		result["properties"] = properties
+		if required != nil {
+			result["required"] = required
+		}
+	}
+
+	if s.items != nil {
+		result["items"] = s.items.schema(options)
+	}
+
+	if len(types) == 1 && types[0] == "string" && len(s.strings) <= options.MaxEnum {
+		repeated := false
+		enum := make([]string, 0, len(s.strings))
+		for v, n := range s.strings {
+			enum = append(enum, v)
+			repeated = repeated || n > 1
+		}
+		if repeated {
+			sort.Strings(enum)
+			list := make([]interface{}, len(enum))
+			for i, v := range enum {
+				list[i] = v
+			}
+			result["enum"] = list
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"github.com/go-test/deep"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		MaxEnum  int
+		Examples []string
+		Expected string
+	}{
+		{
+			Name:     `scalars`,
+			Examples: []string{`{"a":1,"b":1.5,"c":"x","d":true,"e":null}`},
+			Expected: `{
+  "type": "object",
+  "required": ["a", "b", "c", "d", "e"],
+  "properties": {
+    "a": {"type": "integer"},
+    "b": {"type": "number"},
+    "c": {"type": "string"},
+    "d": {"type": "boolean"},
+    "e": {"type": "null"}
+  }
+}`,
+		},
+		{
+			Name: `required and types across examples`,
+			Examples: []string{
+				`{"a":1,"b":"x","c":{"d":1}}`,
+				`{"a":1.5,"b":null,"c":{"e":1}}`,
+			},
+			Expected: `{
+  "type": "object",
+  "required": ["a", "b", "c"],
+  "properties": {
+    "a": {"type": "number"},
+    "b": {"type": ["null", "string"]},
+    "c": {
+      "type": "object",
+      "properties": {
+        "d": {"type": "integer"},
+        "e": {"type": "integer"}
+      }
+    }
+  }
+}`,
+		},
+		{
+			Name:     `array items`,
+			Examples: []string{`{"a":[{"n":"x","v":1},{"n":"y"}],"b":[],"c":[1,"s"]}`},
+			Expected: `{
+  "type": "object",
+  "required": ["a", "b", "c"],
+  "properties": {
+    "a": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["n"],
+        "properties": {
+          "n": {"type": "string"},
+          "v": {"type": "integer"}
+        }
+      }
+    },
+    "b": {"type": "array"},
+    "c": {"type": "array", "items": {"type": ["integer", "string"]}}
+  }
+}`,
+		},
+		{
+			Name:    `enums`,
+			MaxEnum: 2,
+			Examples: []string{
+				`{"mode":"dev","region":"a","name":"x","level":"info"}`,
+				`{"mode":"prod","region":"b","name":"y","level":"info"}`,
+				`{"mode":"dev","region":"c","name":"z"}`,
+			},
+			Expected: `{
+  "type": "object",
+  "required": ["mode", "name", "region"],
+  "properties": {
+    "level": {"type": "string", "enum": ["info"]},
+    "mode": {"type": "string", "enum": ["dev", "prod"]},
+    "name": {"type": "string"},
+    "region": {"type": "string"}
+  }
+}`,
+		},
+		{
+			Name:     `no examples`,
+			Expected: `{}`,
+		},
+	} {
+		var examples []interface{}
+		for _, example := range testCase.Examples {
+			examples = append(examples, parseJSON(t, example))
+		}
+		result := Infer(InferOptions{MaxEnum: testCase.MaxEnum}, examples...)
+		if result["$schema"] != Draft {
+			t.Errorf("%s: unexpected $schema: %v", testCase.Name, result["$schema"])
+		}
+		delete(result, "$schema")
+		if diff := deep.Equal(parseJSON(t, testCase.Expected), interface{}(result)); diff != nil {
+			t.Errorf("%s: %v", testCase.Name, diff)
+		}
+
+		// every example must be valid
+		s, err := Compile(result)
+		if err != nil {
+			t.Errorf("%s: %v", testCase.Name, err)
+			continue
+		}
+		for _, example := range examples {
+			if violations := s.Validate(example); violations != nil {
+				t.Errorf("%s: %v", testCase.Name, violations)
+			}
+		}
+	}
+}
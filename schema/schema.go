@@ -0,0 +1,398 @@
+// Package schema implements validation of values like those returned by parser.Reader, against a JSON Schema, and the
+// inference of a schema from example values, see Infer, as used by the goconfigger command.
+//
+// Only the core keywords of draft 2020-12 are supported: type, required, properties, additionalProperties, items,
+// enum, pattern, minimum, maximum, and $ref, which must refer to a location within the same document, e.g.
+// "#/$defs/port". Any other keyword is ignored. Note that pattern uses the RE2 syntax, see the regexp package.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// Schema is a compiled schema, see Compile.
+	Schema struct {
+		root *node
+	}
+
+	// Violation is a single validation failure, of the value at Path, for the schema Keyword.
+	Violation struct {
+		Path    merge.Path
+		Keyword string
+		Message string
+	}
+
+	node struct {
+		// pointer is the location of the schema within the document, as a JSON Pointer
+		pointer string
+		// always is set for the boolean schemas, true and false
+		always     *bool
+		ref        *node
+		types      []string
+		required   []string
+		properties map[string]*node
+		// additional validates any properties not in properties, where nil allows anything
+		additional *node
+		items      *node
+		enum       []interface{}
+		pattern    *regexp.Regexp
+		minimum    *float64
+		maximum    *float64
+	}
+
+	compiler struct {
+		doc   interface{}
+		nodes map[string]*node
+	}
+)
+
+// types are the valid values of the type keyword.
+var types = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+// Compile compiles a schema document, e.g. as read by parser.Reader, returning an error if it is invalid, or refers
+// to a location that doesn't exist.
+func Compile(doc interface{}) (*Schema, error) {
+	c := &compiler{doc: doc, nodes: make(map[string]*node)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	// a cycle of references, without any other keyword in between, would never terminate
+	pointers := make([]string, 0, len(c.nodes))
+	for pointer := range c.nodes {
+		pointers = append(pointers, pointer)
+	}
+	sort.Strings(pointers)
+	for _, pointer := range pointers {
+		n := c.nodes[pointer]
+		seen := map[*node]bool{n: true}
+		for r := n.ref; r != nil; r = r.ref {
+			if seen[r] {
+				return nil, fmt.Errorf("invalid schema at '%s': circular $ref", n.pointer)
+			}
+			seen[r] = true
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+func (v Violation) Error() string {
+	path := v.Path.String()
+	if path == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", path, v.Message)
+}
+
+// Validate returns every violation of the schema by v, ordered by path, or nil if it is valid.
+func (s *Schema) Validate(v interface{}) []Violation {
+	var violations []Violation
+	s.root.validate(v, make(merge.Path, 0), &violations)
+	return violations
+}
+
+func (c *compiler) compile(v interface{}, pointer string) (*node, error) {
+	if n, ok := c.nodes[pointer]; ok {
+		return n, nil
+	}
+	n := &node{pointer: pointer}
+	c.nodes[pointer] = n
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("invalid schema at '%s': %s", pointer, fmt.Sprintf(format, args...))
+	}
+
+	if b, ok := v.(bool); ok {
+		n.always = &b
+		return n, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fail("expected an object or boolean")
+	}
+
+	if ref, ok := m["$ref"]; ok {
+		s, ok := ref.(string)
+		if !ok {
+			return nil, fail("$ref must be a string")
+		}
+		target, err := c.resolve(s)
+		if err != nil {
+			return nil, fail("%s", err.Error())
+		}
+		n.ref = target
+	}
+
+	if t, ok := m["type"]; ok {
+		var list []interface{}
+		switch t := t.(type) {
+		case string:
+			list = []interface{}{t}
+		case []interface{}:
+			list = t
+		default:
+			return nil, fail("type must be a string or an array")
+		}
+		for _, t := range list {
+			s, ok := t.(string)
+			if !ok || !types[s] {
+				return nil, fail("unknown type %v", t)
+			}
+			n.types = append(n.types, s)
+		}
+	}
+
+	if required, ok := m["required"]; ok {
+		list, ok := required.([]interface{})
+		if !ok {
+			return nil, fail("required must be an array")
+		}
+		for _, r := range list {
+			s, ok := r.(string)
+			if !ok {
+				return nil, fail("required must be an array of strings")
+			}
+			n.required = append(n.required, s)
+		}
+	}
+
+	if properties, ok := m["properties"]; ok {
+		p, ok := properties.(map[string]interface{})
+		if !ok {
+			return nil, fail("properties must be an object")
+		}
+		n.properties = make(map[string]*node, len(p))
+		for k, v := range p {
+			child, err := c.compile(v, pointer+"/properties/"+escapePointer(k))
+			if err != nil {
+				return nil, err
+			}
+			n.properties[k] = child
+		}
+	}
+
+	for _, keyword := range []struct {
+		name   string
+		target **node
+	}{
+		{"additionalProperties", &n.additional},
+		{"items", &n.items},
+	} {
+		if v, ok := m[keyword.name]; ok {
+			child, err := c.compile(v, pointer+"/"+keyword.name)
+			if err != nil {
+				return nil, err
+			}
+			*keyword.target = child
+		}
+	}
+
+	if enum, ok := m["enum"]; ok {
+		list, ok := enum.([]interface{})
+		if !ok {
+			return nil, fail("enum must be an array")
+		}
+		n.enum = list
+	}
+
+	if pattern, ok := m["pattern"]; ok {
+		s, ok := pattern.(string)
+		if !ok {
+			return nil, fail("pattern must be a string")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fail("invalid pattern: %s", err.Error())
+		}
+		n.pattern = re
+	}
+
+	for _, keyword := range []struct {
+		name   string
+		target **float64
+	}{
+		{"minimum", &n.minimum},
+		{"maximum", &n.maximum},
+	} {
+		if v, ok := m[keyword.name]; ok {
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fail("%s must be a number", keyword.name)
+			}
+			*keyword.target = &f
+		}
+	}
+
+	return n, nil
+}
+
+// resolve compiles the schema referred to by ref, which must be a fragment, e.g. "#/$defs/a".
+func (c *compiler) resolve(ref string) (*node, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the document are supported", ref)
+	}
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %s", ref, err.Error())
+	}
+	tokens, err := merge.ParsePointer(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %s", ref, err.Error())
+	}
+	v := c.doc
+	for _, token := range tokens {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+		if v, ok = m[token]; !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return c.compile(v, fragment)
+}
+
+func (n *node) validate(v interface{}, path merge.Path, violations *[]Violation) {
+	fail := func(path merge.Path, keyword, format string, args ...interface{}) {
+		*violations = append(*violations, Violation{
+			Path:    append(make(merge.Path, 0, len(path)), path...),
+			Keyword: keyword,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			fail(path, "false", "no value is allowed")
+		}
+		return
+	}
+
+	if n.ref != nil {
+		n.ref.validate(v, path, violations)
+	}
+
+	if n.types != nil {
+		valid := false
+		for _, t := range n.types {
+			if t == typeOf(v) || (t == "number" && typeOf(v) == "integer") {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			fail(path, "type", "expected %s, got %s", strings.Join(n.types, " or "), typeOf(v))
+			// the remaining keywords are only meaningful for the expected types
+			return
+		}
+	}
+
+	if n.enum != nil {
+		valid := false
+		for _, e := range n.enum {
+			if reflect.DeepEqual(v, e) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			fail(path, "enum", "%s is not one of %s", formatValue(v), formatValue(n.enum))
+		}
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range n.required {
+			if _, ok := t[k]; !ok {
+				fail(path, "required", "missing required property %q", k)
+			}
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := append(path, merge.Segment{Value: k})
+			if p, ok := n.properties[k]; ok {
+				p.validate(t[k], child, violations)
+			} else if n.additional != nil {
+				if n.additional.always != nil && !*n.additional.always {
+					fail(child, "additionalProperties", "property %q is not allowed", k)
+				} else {
+					n.additional.validate(t[k], child, violations)
+				}
+			}
+		}
+
+	case []interface{}:
+		if n.items != nil {
+			for i, e := range t {
+				n.items.validate(e, append(path, merge.Segment{Value: fmt.Sprint(i), Index: true}), violations)
+			}
+		}
+
+	case string:
+		if n.pattern != nil && !n.pattern.MatchString(t) {
+			fail(path, "pattern", "%s doesn't match the pattern %q", formatValue(t), n.pattern.String())
+		}
+
+	case float64:
+		if n.minimum != nil && t < *n.minimum {
+			fail(path, "minimum", "%s is less than the minimum %s", formatValue(t), formatValue(*n.minimum))
+		}
+		if n.maximum != nil && t > *n.maximum {
+			fail(path, "maximum", "%s is greater than the maximum %s", formatValue(t), formatValue(*n.maximum))
+		}
+	}
+}
+
+// typeOf returns the schema type of v, where whole numbers are an integer.
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func formatValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
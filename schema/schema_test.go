@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/go-test/deep"
+	"strings"
+	"testing"
+)
+
+func parseJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSchema_Validate(t *testing.T) {
+	const service = `{
+  "type": "object",
+  "required": ["name", "port"],
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z]+$"},
+    "port": {"$ref": "#/$defs/port"},
+    "mode": {"enum": ["dev", "prod", null]},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "limits": {"type": "object", "additionalProperties": {"type": "number", "minimum": 0}}
+  },
+  "additionalProperties": false,
+  "$defs": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  }
+}`
+	for _, testCase := range []struct {
+		Schema, Value string
+		Violations    []string
+	}{
+		{service, `{"name": "api", "port": 80}`, nil},
+		{service, `{"name": "api", "port": 80, "mode": null, "tags": [], "limits": {"cpu": 0.5}}`, nil},
+		{
+			service,
+			`{"name": "API", "port": 80.5, "mode": "test", "tags": ["a", 1], "limits": {"cpu": -1, "mem": "1G"}, "x": 1}`,
+			[]string{
+				`limits.cpu: -1 is less than the minimum 0`,
+				`limits.mem: expected number, got string`,
+				`mode: "test" is not one of ["dev","prod",null]`,
+				`name: "API" doesn't match the pattern "^[a-z]+$"`,
+				`port: expected integer, got number`,
+				`tags[1]: expected string, got integer`,
+				`x: property "x" is not allowed`,
+			},
+		},
+		{service, `{"port": 0}`, []string{`missing required property "name"`, `port: 0 is less than the minimum 1`}},
+		{service, `{"name": "api", "port": 65536}`, []string{`port: 65536 is greater than the maximum 65535`}},
+		{service, `[]`, []string{`expected object, got array`}},
+		{`{"type": ["string", "null"]}`, `null`, nil},
+		{`{"type": ["string", "null"]}`, `1`, []string{`expected string or null, got integer`}},
+		{`{"type": "number"}`, `1`, nil},
+		{`true`, `{"a": 1}`, nil},
+		{`false`, `1`, []string{`no value is allowed`}},
+		{`{"properties": {"a": false}}`, `{"a": 1}`, []string{`a: no value is allowed`}},
+		{`{"required": ["a"]}`, `"not an object"`, nil},
+		{`{"enum": [{"a": [1]}]}`, `{"a": [1]}`, nil},
+		{
+			`{"type": "object", "properties": {"child": {"$ref": "#"}, "name": {"type": "string"}}}`,
+			`{"child": {"child": {"name": 1}}}`,
+			[]string{`child.child.name: expected string, got integer`},
+		},
+		{
+			`{"items": {"$ref": "#/$defs/a~1b"}, "$defs": {"a/b": {"type": "boolean"}}}`,
+			`[true, "x"]`,
+			[]string{`[1]: expected boolean, got string`},
+		},
+		{
+			`{"properties": {"a.b": {"type": "string"}}}`,
+			`{"a.b": 1}`,
+			[]string{`["a.b"]: expected string, got integer`},
+		},
+	} {
+		s, err := Compile(parseJSON(t, testCase.Schema))
+		if err != nil {
+			t.Errorf("%s: %v", testCase.Schema, err)
+			continue
+		}
+		var violations []string
+		for _, v := range s.Validate(parseJSON(t, testCase.Value)) {
+			violations = append(violations, v.Error())
+		}
+		if diff := deep.Equal(testCase.Violations, violations); diff != nil {
+			t.Errorf("%s %s: %v\n%s", testCase.Schema, testCase.Value, diff, strings.Join(violations, "\n"))
+		}
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	for _, testCase := range []struct {
+		Schema, Error string
+	}{
+		{`1`, `invalid schema at '': expected an object or boolean`},
+		{`{"type": "int"}`, `invalid schema at '': unknown type int`},
+		{`{"properties": {"a": {"type": 1}}}`, `invalid schema at '/properties/a': type must be a string or an array`},
+		{`{"required": [1]}`, `invalid schema at '': required must be an array of strings`},
+		{`{"pattern": "("}`, "invalid schema at '': invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{`{"minimum": "1"}`, `invalid schema at '': minimum must be a number`},
+		{`{"items": {"$ref": "#/$defs/missing"}}`, `invalid schema at '/items': $ref "#/$defs/missing" not found`},
+		{`{"$ref": "other.json#/a"}`, `invalid schema at '': unsupported $ref "other.json#/a": only references within the document are supported`},
+		{`{"$ref": "#/$defs/a", "$defs": {"a": {"$ref": "#"}}}`, `invalid schema at '': circular $ref`},
+	} {
+		if _, err := Compile(parseJSON(t, testCase.Schema)); err == nil || err.Error() != testCase.Error {
+			t.Errorf("%s: unexpected error: %v", testCase.Schema, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package configger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/joeycumines/go-configger/merge"
+	"reflect"
+	"time"
+)
+
+// Value is a navigable view of part of a value like those returned by parser.Reader, see NewValue, with typed
+// accessors, which convert values like Decode. Paths use the same syntax as the goconfigger command, e.g. a.b[0]["c.d"],
+// and are relative to the Value. Values returned by Get share the underlying value, such that changes made via Set
+// and Delete are visible to every Value.
+//
+// Each accessor returns the zero value if the path doesn't exist, or can't be converted, and has an error returning
+// variant, e.g. IntE, where a path that doesn't exist is a *DecodeError of ErrNotFound. Null converts to the zero
+// value, without an error.
+type Value struct {
+	root *interface{}
+	path merge.Path
+	// err is set if the path was invalid
+	err error
+}
+
+// ErrNotFound is the error of the *DecodeError returned by the Value accessors, for paths that don't exist.
+var ErrNotFound = errors.New("not found")
+
+// NewValue returns a Value for the root of v.
+func NewValue(v interface{}) Value {
+	return Value{root: &v, path: make(merge.Path, 0)}
+}
+
+// Get returns the Value at path, which may not exist, or an empty path for v itself.
+func (v Value) Get(path string) Value {
+	if v.err != nil {
+		return v
+	}
+	p, err := v.resolve(path)
+	if err != nil {
+		return Value{root: v.root, path: v.path, err: err}
+	}
+	return Value{root: v.root, path: p}
+}
+
+// Path returns the path of v, from the root.
+func (v Value) Path() string { return v.path.String() }
+
+// Exists returns true if the path of v exists, including if it is null.
+func (v Value) Exists() bool {
+	_, ok := v.lookup()
+	return ok
+}
+
+// Interface returns the underlying value, or nil if it doesn't exist.
+func (v Value) Interface() interface{} {
+	data, _ := v.lookup()
+	return data
+}
+
+// String returns the value as a string, where numbers and booleans are formatted.
+func (v Value) String() string {
+	s, _ := v.StringE()
+	return s
+}
+
+// StringE is like String, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) StringE() (s string, err error) {
+	err = v.decode(&s)
+	return
+}
+
+// Int returns the value as an int, where strings are parsed.
+func (v Value) Int() int {
+	i, _ := v.IntE()
+	return i
+}
+
+// IntE is like Int, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) IntE() (i int, err error) {
+	err = v.decode(&i)
+	return
+}
+
+// Float returns the value as a float64, where strings are parsed.
+func (v Value) Float() float64 {
+	f, _ := v.FloatE()
+	return f
+}
+
+// FloatE is like Float, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) FloatE() (f float64, err error) {
+	err = v.decode(&f)
+	return
+}
+
+// Bool returns the value as a bool, where strings are parsed, see strconv.ParseBool.
+func (v Value) Bool() bool {
+	b, _ := v.BoolE()
+	return b
+}
+
+// BoolE is like Bool, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) BoolE() (b bool, err error) {
+	err = v.decode(&b)
+	return
+}
+
+// Duration returns the value as a time.Duration, where strings are parsed (see time.ParseDuration), and numbers are
+// nanoseconds.
+func (v Value) Duration() time.Duration {
+	d, _ := v.DurationE()
+	return d
+}
+
+// DurationE is like Duration, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) DurationE() (d time.Duration, err error) {
+	err = v.decode(&d)
+	return
+}
+
+// StringSlice returns the value as a []string, which must be an array, see String.
+func (v Value) StringSlice() []string {
+	s, _ := v.StringSliceE()
+	return s
+}
+
+// StringSliceE is like StringSlice, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) StringSliceE() (s []string, err error) {
+	err = v.decode(&s)
+	return
+}
+
+// Map returns the Value of each key, if the value is an object.
+func (v Value) Map() map[string]Value {
+	m, _ := v.MapE()
+	return m
+}
+
+// MapE is like Map, but returns an error if the path doesn't exist, or the value can't be converted.
+func (v Value) MapE() (map[string]Value, error) {
+	var m map[string]interface{}
+	if err := v.decode(&m); err != nil || m == nil {
+		return nil, err
+	}
+	result := make(map[string]Value, len(m))
+	for k := range m {
+		result[k] = Value{root: v.root, path: append(copyPath(v.path), merge.Segment{Value: k})}
+	}
+	return result, nil
+}
+
+// Set sets the value at path, creating any missing objects or arrays, see merge.Mode.Set, where the value is
+// converted as if it were encoded, then decoded, as json, e.g. an int becomes a float64.
+func (v Value) Set(path string, value interface{}) error {
+	if v.err != nil {
+		return v.err
+	}
+	p, err := v.resolve(path)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return err
+	}
+	result, err := merge.NewMode().Set(*v.root, p, normalized)
+	if err != nil {
+		return err
+	}
+	*v.root = result
+	return nil
+}
+
+// Delete removes the value at path, if it exists, where removing an array element shifts any elements that follow
+// it, see merge.Mode.Unset.
+func (v Value) Delete(path string) error {
+	if v.err != nil {
+		return v.err
+	}
+	p, err := v.resolve(path)
+	if err != nil {
+		return err
+	}
+	result, err := merge.NewMode().Unset(*v.root, p)
+	if err != nil {
+		return err
+	}
+	*v.root = result
+	return nil
+}
+
+// resolve parses path, which must be literal, returning the path from the root.
+func (v Value) resolve(path string) (merge.Path, error) {
+	if v.root == nil {
+		return nil, errors.New("uninitialized value, see NewValue")
+	}
+	p, err := merge.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Literal() {
+		return nil, fmt.Errorf("invalid path '%s': wildcards aren't supported", path)
+	}
+	return append(copyPath(v.path), p...), nil
+}
+
+func (v Value) lookup() (interface{}, bool) {
+	if v.err != nil || v.root == nil {
+		return nil, false
+	}
+	data, ok, _ := merge.Get(*v.root, v.path)
+	return data, ok
+}
+
+// decode decodes the value into target, which must be a pointer, see Decode.
+func (v Value) decode(target interface{}) error {
+	if v.err != nil {
+		return v.err
+	}
+	data, ok := v.lookup()
+	if !ok {
+		return &DecodeError{Path: copyPath(v.path), Err: ErrNotFound}
+	}
+	return decodeValue(data, reflect.ValueOf(target).Elem(), copyPath(v.path))
+}
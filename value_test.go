@@ -0,0 +1,136 @@
+package configger
+
+import (
+	"errors"
+	"github.com/go-test/deep"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	v := NewValue(parseJSON(t, `{
+  "name": "app",
+  "port": "8080",
+  "ratio": 0.5,
+  "debug": "true",
+  "timeout": "1m30s",
+  "hosts": ["a", "b", 3],
+  "db": {"host": "x", "opts": {"a.b": 1}},
+  "empty": null
+}`))
+
+	if s := v.Get(`name`).String(); s != `app` {
+		t.Errorf("unexpected name: %q", s)
+	}
+	if i := v.Get(`port`).Int(); i != 8080 {
+		t.Errorf("unexpected port: %d", i)
+	}
+	if f := v.Get(`ratio`).Float(); f != 0.5 {
+		t.Errorf("unexpected ratio: %v", f)
+	}
+	if b := v.Get(`debug`).Bool(); !b {
+		t.Error("unexpected debug")
+	}
+	if d := v.Get(`timeout`).Duration(); d != 90*time.Second {
+		t.Errorf("unexpected timeout: %v", d)
+	}
+	if diff := deep.Equal([]string{`a`, `b`, `3`}, v.Get(`hosts`).StringSlice()); diff != nil {
+		t.Error(diff)
+	}
+	if s := v.Get(`hosts[1]`).String(); s != `b` {
+		t.Errorf("unexpected hosts[1]: %q", s)
+	}
+	if s := v.Get(`hosts.1`).String(); s != `b` {
+		t.Errorf("unexpected hosts.1: %q", s)
+	}
+	if i := v.Get(`db`).Get(`opts["a.b"]`).Int(); i != 1 {
+		t.Errorf("unexpected db.opts: %d", i)
+	}
+	if m := v.Get(`db`).Map(); len(m) != 2 || m[`host`].String() != `x` || m[`opts`].Path() != `db.opts` {
+		t.Errorf("unexpected db: %v", m)
+	}
+	if !v.Get(`empty`).Exists() || v.Get(`missing`).Exists() || v.Get(`hosts[3]`).Exists() {
+		t.Error("unexpected exists")
+	}
+	if s, err := v.Get(`empty`).StringE(); s != `` || err != nil {
+		t.Errorf("unexpected empty: %q %v", s, err)
+	}
+	if v.Get(``).Interface() == nil {
+		t.Error("expected the root")
+	}
+
+	// errors
+	if _, err := v.Get(`db.port`).IntE(); !errors.Is(err, ErrNotFound) || err.Error() != `db.port: not found` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := v.Get(`name`).IntE(); err == nil || err.Error() != `name: unable to decode string "app" into int: strconv.ParseFloat: parsing "app": invalid syntax` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := v.Get(`db`).StringSliceE(); err == nil || err.Error() != `db: unable to decode object into []string` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := v.Get(`hosts`).MapE(); err == nil || err.Error() != `hosts: unable to decode array into map[string]interface {}` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := v.Get(`hosts[*]`).StringE(); err == nil || err.Error() != `invalid path 'hosts[*]': wildcards aren't supported` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := v.Get(`a..b`).Get(`c`).BoolE(); err == nil {
+		t.Error("expected an error")
+	}
+	if i := v.Get(`name`).Int(); i != 0 {
+		t.Errorf("unexpected int: %d", i)
+	}
+}
+
+func TestValue_Set(t *testing.T) {
+	v := NewValue(parseJSON(t, `{"db": {"host": "x"}, "hosts": ["a", "b", "c"]}`))
+	db := v.Get(`db`)
+
+	for _, err := range []error{
+		db.Set(`port`, 5432),
+		db.Set(`opts.timeout`, 2*time.Second),
+		v.Set(`tags[1]`, []string{`x`}),
+		v.Delete(`hosts[0]`),
+		v.Delete(`missing.path`),
+		v.Set(`db.host`, map[string]bool{`y`: true}),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := deep.Equal(parseJSON(t, `{
+  "db": {"host": {"y": true}, "port": 5432, "opts": {"timeout": 2000000000}},
+  "hosts": ["b", "c"],
+  "tags": [null, ["x"]]
+}`), v.Interface()); diff != nil {
+		t.Error(diff)
+	}
+	if d := db.Get(`opts.timeout`).Duration(); d != 2*time.Second {
+		t.Errorf("unexpected timeout: %v", d)
+	}
+
+	if err := db.Delete(``); err != nil {
+		t.Fatal(err)
+	}
+	if db.Exists() || !v.Exists() {
+		t.Error("unexpected exists")
+	}
+	if err := v.Delete(``); err != nil {
+		t.Fatal(err)
+	}
+	if v.Interface() != nil {
+		t.Errorf("unexpected value: %v", v.Interface())
+	}
+
+	if err := v.Set(`a[*]`, 1); err == nil {
+		t.Error("expected an error")
+	}
+	if err := v.Set(`a`, func() {}); err == nil {
+		t.Error("expected an error")
+	}
+	if err := (Value{}).Set(`a`, 1); err == nil || err.Error() != `uninitialized value, see NewValue` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}